@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/database"
+)
+
+// Store metadata of apps doesn't depend on who asks for it, so it's kept in Postgres where every replica
+// shares it and it survives restarts
+type appDetailsStore struct {
+	db *database.Queries
+}
+
+func (store appDetailsStore) LoadAppDetails(ctx context.Context, appIDs []int, fetchedAfter time.Time) ([]api.AppDetails, error) {
+	ids := make([]int32, 0, len(appIDs))
+	for _, appID := range appIDs {
+		ids = append(ids, int32(appID))
+	}
+
+	rows, err := store.db.ListAppDetails(ctx, database.ListAppDetailsParams{
+		AppIds:       ids,
+		FetchedAfter: fetchedAfter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]api.AppDetails, 0, len(rows))
+	for _, row := range rows {
+		appDetails := api.AppDetails{
+			AppID:       int(row.AppID),
+			Found:       row.Found,
+			Name:        row.Name,
+			Type:        row.Type,
+			IsFree:      row.IsFree,
+			Genres:      row.Genres,
+			Categories:  row.Categories,
+			Multiplayer: row.Multiplayer,
+			Coop:        row.Coop,
+			FetchedAt:   row.FetchedAt,
+		}
+		if row.PriceCurrency.Valid {
+			appDetails.Price = &api.Price{
+				Currency:        row.PriceCurrency.String,
+				InitialCents:    int(row.PriceInitial.Int32),
+				FinalCents:      int(row.PriceFinal.Int32),
+				DiscountPercent: int(row.DiscountPercent.Int32),
+			}
+		}
+		details = append(details, appDetails)
+	}
+
+	return details, nil
+}
+
+func (store appDetailsStore) SaveAppDetails(ctx context.Context, details api.AppDetails) error {
+	params := database.UpsertAppDetailsParams{
+		AppID:       int32(details.AppID),
+		Found:       details.Found,
+		Name:        details.Name,
+		Type:        details.Type,
+		IsFree:      details.IsFree,
+		Genres:      details.Genres,
+		Categories:  details.Categories,
+		Multiplayer: details.Multiplayer,
+		Coop:        details.Coop,
+		FetchedAt:   details.FetchedAt,
+	}
+	if details.Price != nil {
+		params.PriceCurrency = sql.NullString{String: details.Price.Currency, Valid: true}
+		params.PriceInitial = sql.NullInt32{Int32: int32(details.Price.InitialCents), Valid: true}
+		params.PriceFinal = sql.NullInt32{Int32: int32(details.Price.FinalCents), Valid: true}
+		params.DiscountPercent = sql.NullInt32{Int32: int32(details.Price.DiscountPercent), Valid: true}
+	}
+
+	return store.db.UpsertAppDetails(ctx, params)
+}
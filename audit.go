@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	auditLoginSucceeded         = "login_succeeded"
+	auditLoginFailed            = "login_failed"
+	auditLoginLocked            = "login_locked"
+	auditReauthFailed           = "reauth_failed"
+	auditLogout                 = "logout"
+	auditTokenRevoked           = "token_revoked"
+	auditPasswordChanged        = "password_changed"
+	auditUsernameChanged        = "username_changed"
+	auditSteamIDChanged         = "steam_id_changed"
+	auditEmailChanged           = "email_changed"
+	auditEmailVerified          = "email_verified"
+	auditPasswordResetRequested = "password_reset_requested"
+	auditPasswordReset          = "password_reset"
+	auditMFAEnabled             = "mfa_enabled"
+	auditMFADisabled            = "mfa_disabled"
+	auditRecoveryCodeUsed       = "recovery_code_used"
+	auditUserDisabled           = "user_disabled"
+	auditUserEnabled            = "user_enabled"
+	auditUserDeleted            = "user_deleted"
+	auditRoleChanged            = "role_changed"
+	auditAccountDeleted         = "account_deleted"
+	auditDataExported           = "data_exported"
+)
+
+type AuditEvent struct {
+	ID        int64           `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	UserID    *uuid.UUID      `json:"user_id"`
+	ActorID   *uuid.UUID      `json:"actor_id"`
+	EventType string          `json:"event_type"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	Details   json.RawMessage `json:"details"`
+}
+
+// Records an event about userID's account. The actor is whoever is logged in for the request, which is
+// the user themselves unless an admin did it, and is left empty for events like a failed login.
+// Failing to write an event is only logged, it shouldn't break the action being audited
+func (cfg *config) recordAuditEvent(req *http.Request, userID uuid.UUID, eventType string, details map[string]any) {
+	err := cfg.db.CreateAuditEvent(req.Context(), auditEventParams(req, userID, eventType, details))
+	if err != nil {
+		log.Printf("Unable to record audit event %s for user %s: %v", eventType, userID, err)
+	}
+}
+
+// Helper function that builds an audit event for the request, for writing it inside a transaction
+func auditEventParams(req *http.Request, userID uuid.UUID, eventType string, details map[string]any) database.CreateAuditEventParams {
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	encodedDetails, err := json.Marshal(details)
+	if err != nil {
+		log.Printf("Unable to encode audit event details for %s: %v", eventType, err)
+		encodedDetails = []byte("{}")
+	}
+
+	actorID, _ := req.Context().Value(userIDContextKey).(uuid.UUID)
+
+	return database.CreateAuditEventParams{
+		CreatedAt: time.Now().UTC(),
+		UserID:    uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		ActorID:   uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil},
+		EventType: eventType,
+		Ip:        clientIP(req),
+		UserAgent: req.UserAgent(),
+		Details:   encodedDetails,
+	}
+}
+
+func databaseAuditEventsToAuditEvents(events []database.AuditEvent) []AuditEvent {
+	converted := make([]AuditEvent, 0, len(events))
+	for _, event := range events {
+		auditEvent := AuditEvent{
+			ID:        event.ID,
+			CreatedAt: event.CreatedAt,
+			EventType: event.EventType,
+			IP:        event.Ip,
+			UserAgent: event.UserAgent,
+			Details:   event.Details,
+		}
+		if event.UserID.Valid {
+			auditEvent.UserID = &event.UserID.UUID
+		}
+		if event.ActorID.Valid {
+			auditEvent.ActorID = &event.ActorID.UUID
+		}
+		converted = append(converted, auditEvent)
+	}
+	return converted
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+const commandPromoteAdmin = "promote-admin"
+
+// Runs a one-off command given on the command line instead of starting the server
+func runCommand(args []string) error {
+	switch args[0] {
+	case commandPromoteAdmin:
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s <username>", commandPromoteAdmin)
+		}
+		return runPromoteAdmin(args[1])
+	default:
+		return fmt.Errorf("unknown command %q, the only command is %s", args[0], commandPromoteAdmin)
+	}
+}
+
+func runPromoteAdmin(username string) error {
+	if err := godotenv.Load(".env"); err != nil {
+		fmt.Printf("warning: assuming default configuration. .env unreadable: %v\n", err)
+	}
+
+	db, err := sql.Open("postgres", getEnvOrFail("DATABASE_URL"))
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	cfg := &config{
+		db:    database.New(db),
+		sqlDB: db,
+	}
+
+	userID, err := cfg.promoteAdmin(context.Background(), username)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s (%s) is now an admin\n", username, userID)
+	return nil
+}
+
+// Promotes an existing account to admin, this is how the first admin gets created. It only ever acts on an
+// account that exists when the command is run, registration is open so a username can't be promoted in advance
+func (cfg *config) promoteAdmin(ctx context.Context, username string) (uuid.UUID, error) {
+	user, err := cfg.db.GetUserByUsername(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("no account is named %q, create it first", username)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("looking up %q: %w", username, err)
+	}
+
+	if user.Role == roleAdmin {
+		return user.ID, nil
+	}
+
+	_, err = cfg.db.SetUserRoleByUsername(ctx, database.SetUserRoleByUsernameParams{
+		Username:  username,
+		Role:      roleAdmin,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("promoting %q: %w", username, err)
+	}
+
+	encodedDetails := []byte(`{"role":"admin","source":"command_line"}`)
+	err = cfg.db.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		CreatedAt: time.Now().UTC(),
+		UserID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+		EventType: auditRoleChanged,
+		Details:   encodedDetails,
+	})
+	if err != nil {
+		fmt.Printf("warning: unable to record audit event for promoting %q: %v\n", username, err)
+	}
+
+	return user.ID, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/auth"
+)
+
+// CSRF protection uses the double-submit pattern: the token is set as a cookie and the client has to
+// echo it back in the X-CSRF-Token header. Another site can make the browser send the cookie but it
+// can't read it, so it can't put the matching header on a forged request
+const (
+	csrfCookieName = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+var (
+	errCSRFOrigin  = errors.New("request origin is not allowed")
+	errCSRFMissing = errors.New("missing CSRF token")
+	errCSRFInvalid = errors.New("CSRF token does not match")
+)
+
+// Hands out the CSRF token for the browser, reusing the one already in the cookie so other tabs keep working.
+// Clients on another domain can't read the cookie themselves so they fetch it from here
+func (cfg *config) handlerCSRFToken(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		CSRFToken string `json:"csrf_token"`
+	}
+
+	token := ""
+	if cookie, err := req.Cookie(csrfCookieName); err == nil {
+		token = cookie.Value
+	}
+
+	if token == "" {
+		var err error
+		token, err = auth.MakeRefreshToken()
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "Unable to create CSRF token", err)
+			return
+		}
+	}
+
+	setCSRFCookie(w, token)
+	w.Header().Set("Cache-Control", "no-store")
+	api.RespondWithJSON(w, http.StatusOK, response{
+		CSRFToken: token,
+	})
+}
+
+// Checks a cookie-authenticated request, reads never change state so only unsafe methods are checked
+func (cfg *config) checkCSRF(req *http.Request) error {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	// Browsers always send Origin on cross-site requests, so one that isn't allowed is rejected outright
+	origin := req.Header.Get("Origin")
+	if origin != "" && !cfg.originAllowed(origin) && !sameOrigin(origin, req) {
+		return errCSRFOrigin
+	}
+
+	cookie, err := req.Cookie(csrfCookieName)
+	if err != nil || cookie.Value == "" {
+		return errCSRFMissing
+	}
+
+	header := req.Header.Get(csrfHeaderName)
+	if header == "" {
+		return errCSRFMissing
+	}
+
+	if subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+		return errCSRFInvalid
+	}
+
+	return nil
+}
+
+func (cfg *config) originAllowed(origin string) bool {
+	for _, allowed := range cfg.allowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function for pages served by the backend itself, like the dev index page
+func sameOrigin(origin string, req *http.Request) bool {
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(originURL.Host, req.Host)
+}
+
+// The cookie isn't HttpOnly on purpose, a frontend on the same site reads it to fill in the header
+func setCSRFCookie(w http.ResponseWriter, token string) {
+	// get platform to determine if dev or prod, if dev make devPlatform false for secure cookies
+	devPlatform := os.Getenv("PLATFORM") != "dev"
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: false,
+		Secure:   devPlatform,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(15 * 24 * time.Hour),
+	})
+}
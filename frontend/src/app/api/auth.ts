@@ -48,6 +48,20 @@ export async function login(email: string, password: string) {
   }
 }
 
+// The backend wants the CSRF token echoed back in a header on every change made with the session cookies
+async function csrfHeaders(): Promise<Record<string, string>> {
+  const url = new URL("users/csrf", backendURL);
+  const resp = await fetch(url.toString(), {
+    method: "GET",
+    credentials: "include",
+  });
+  if (resp.status >= 400) {
+    throw new Error(`Unable to get CSRF token, failed with status ${resp.status}`);
+  }
+  const data = await resp.json();
+  return { "X-CSRF-Token": data.csrf_token };
+}
+
 export async function logout() {
   if (!backendURL) {
     throw new Error("Backend base URL not set up in environment variables")
@@ -58,6 +72,7 @@ export async function logout() {
     const resp = await fetch(url.toString(), {
       method: "POST",
       credentials: "include",
+      headers: await csrfHeaders(),
     });
 
     if (resp.status >= 400) {
@@ -80,7 +95,7 @@ export async function editAccount(payload: EditAccountPayload) {
     const resp = await fetch(url.toString(), {
       method: "PATCH",
       credentials: "include",
-      headers: { "Content-Type": "application/json" },
+      headers: { "Content-Type": "application/json", ...(await csrfHeaders()) },
       body: JSON.stringify(payload),
     });
 
@@ -44,4 +44,5 @@ export type EditAccountPayload = {
   username?: string;
   password?: string;
   steam_id?: string;
+  current_password?: string;
 };
@@ -10,7 +10,7 @@ export default function EditAccountPage() {
   const { auth, checkAuth } = useAuth();
   const router = useRouter();
 
-  const [fields, setFields] = useState({ username: "", password: "", steam_id: "" });
+  const [fields, setFields] = useState({ username: "", password: "", steam_id: "", current_password: "" });
   const [original, setOriginal] = useState({ username: "", steam_id: "" });
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState("");
@@ -30,7 +30,8 @@ export default function EditAccountPage() {
         setFields({
           username: data.user?.username ?? "",
           password: "",
-          steam_id: data.user?.steam_id ?? ""
+          steam_id: data.user?.steam_id ?? "",
+          current_password: ""
         });
         setOriginal({
           username: data.user?.username ?? "",
@@ -60,11 +61,12 @@ export default function EditAccountPage() {
         setLoading(false);
         return;
     }
+    payload.current_password = fields.current_password;
 
     try {
         await editAccount(payload);
         setSuccess("Account updated!");
-        setFields({ ...fields, password: "" });
+        setFields({ ...fields, password: "", current_password: "" });
         checkAuth();
         setLoading(false);
         router.push("/");
@@ -128,7 +130,7 @@ export default function EditAccountPage() {
               style={{ marginTop: 4 }}
             />
           </label>
-          <label style={{ display: "block", marginBottom: 16 }}>
+          <label style={{ display: "block", marginBottom: 10 }}>
             <h3 className={editStyles.fieldlabel}>Steam ID:</h3>
             <input
               className={styles.modalInput}
@@ -139,6 +141,19 @@ export default function EditAccountPage() {
               required
               style={{ marginTop: 4 }}
             />
+          </label>
+          <label style={{ display: "block", marginBottom: 16 }}>
+            <h3 className={editStyles.fieldlabel}>Current Password:</h3>
+            <input
+              className={styles.modalInput}
+              type="password"
+              name="current_password"
+              value={fields.current_password}
+              onChange={e => setFields({ ...fields, current_password: e.target.value })}
+              placeholder="(required to save changes)"
+              required
+              style={{ marginTop: 4 }}
+            />
           </label>
             <div style={{ display: "flex", gap: 10, marginTop: 10 }}>
                 <button className={styles.formButton} type="submit" disabled={loading}>
@@ -11,3 +11,5 @@ require (
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.38.0
 )
+
+require golang.org/x/sys v0.33.0 // indirect
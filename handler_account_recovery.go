@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/auth"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/Khazz0r/steam-lens/internal/mailer"
+	"github.com/Khazz0r/steam-lens/internal/validate"
+	"github.com/google/uuid"
+)
+
+const (
+	tokenPurposeVerifyEmail   = "verify_email"
+	tokenPurposePasswordReset = "password_reset"
+
+	verifyEmailTokenExpiry   = 24 * time.Hour
+	passwordResetTokenExpiry = time.Hour
+
+	// How long a password reset email may take to send after the request has been answered
+	passwordResetSendTimeout = time.Minute
+)
+
+// Sets or changes the account email. The new address is kept as pending until the link sent to it is used, and
+// a verified address already on the account stays in use until then. Whoever controls the email can reset the
+// password, so the current password is needed and the old verified address is told
+func (cfg *config) handlerSetEmail(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Email           string `json:"email"`
+		CurrentPassword string `json:"current_password"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Email("email", strings.TrimSpace(params.Email))
+		v.Required("current_password", params.CurrentPassword)
+	}) {
+		return
+	}
+
+	currentUser, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", err)
+		return
+	}
+
+	_, err = cfg.passwords.Check(currentUser.HashedPassword, params.CurrentPassword)
+	if err != nil {
+		cfg.recordAuditEvent(req, userID, auditReauthFailed, nil)
+		api.RespondWithAppError(w, api.NewError(http.StatusUnauthorized, api.CodeInvalidCredentials, "Current password is incorrect", err))
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(params.Email))
+
+	// Asking for the verified address again just cancels any pending change
+	if currentUser.EmailVerifiedAt.Valid && currentUser.Email.String == email {
+		err = cfg.db.SetPendingEmail(req.Context(), database.SetPendingEmailParams{
+			ID:           userID,
+			PendingEmail: sql.NullString{},
+			UpdatedAt:    time.Now().UTC(),
+		})
+		if err != nil {
+			api.RespondWithAppError(w, api.DatabaseError(err, "Unable to set email"))
+			return
+		}
+
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{
+			"message": "Email is already verified",
+		})
+		return
+	}
+
+	err = cfg.db.SetPendingEmail(req.Context(), database.SetPendingEmailParams{
+		ID:           userID,
+		PendingEmail: sql.NullString{String: email, Valid: true},
+		UpdatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to set email"))
+		return
+	}
+
+	cfg.recordAuditEvent(req, userID, auditEmailChanged, map[string]any{"email": email})
+
+	if currentUser.EmailVerifiedAt.Valid {
+		err = cfg.sendEmailChangedNotice(req.Context(), currentUser, email)
+		if err != nil {
+			log.Printf("Unable to send email change notice for user %s: %v", userID, err)
+		}
+	}
+
+	token, err := cfg.issueAccountToken(req.Context(), userID, tokenPurposeVerifyEmail, verifyEmailTokenExpiry)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to create verification token", err)
+		return
+	}
+
+	err = cfg.mailer.Send(req.Context(), mailer.Message{
+		To:      email,
+		Subject: "Verify your Steam Lens email",
+		Body: fmt.Sprintf("Use the link below to verify your email address, it expires in %d hours.\n\n%s\n",
+			int(verifyEmailTokenExpiry.Hours()), cfg.accountTokenLink("verify-email", token)),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadGateway, "Unable to send verification email", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message": "Verification email sent",
+	})
+}
+
+func (cfg *config) handlerVerifyEmail(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Required("token", params.Token)
+	}) {
+		return
+	}
+
+	userID, err := cfg.db.ConsumeAccountToken(req.Context(), database.ConsumeAccountTokenParams{
+		Now:       time.Now().UTC(),
+		TokenHash: auth.HashToken(params.Token),
+		Purpose:   tokenPurposeVerifyEmail,
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "Verification link is invalid or has expired", err)
+		return
+	}
+
+	// Someone else may have verified the same address in the meantime, which the unique index reports as taken
+	verified, err := cfg.db.MarkEmailVerified(req.Context(), database.MarkEmailVerifiedParams{
+		ID:              userID,
+		EmailVerifiedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to verify email"))
+		return
+	}
+	if verified == 0 {
+		api.RespondWithError(w, http.StatusBadRequest, "There is no email waiting to be verified", nil)
+		return
+	}
+
+	cfg.recordAuditEvent(req, userID, auditEmailVerified, nil)
+
+	api.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Email verified",
+	})
+}
+
+// Sends a reset link to the account's verified email. It always responds the same way so it
+// can't be used to find out which usernames or emails are registered, the token, audit event and
+// email are handled after responding so the response time doesn't give it away either
+func (cfg *config) handlerRequestPasswordReset(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		if strings.TrimSpace(params.Email) != "" {
+			v.Email("email", strings.TrimSpace(params.Email))
+		} else {
+			v.Required("username", params.Username)
+		}
+	}) {
+		return
+	}
+
+	var user database.User
+	var err error
+	if strings.TrimSpace(params.Email) != "" {
+		user, err = cfg.db.GetUserByEmail(req.Context(), sql.NullString{String: strings.ToLower(strings.TrimSpace(params.Email)), Valid: true})
+	} else {
+		user, err = cfg.db.GetUserByUsername(req.Context(), params.Username)
+	}
+
+	if err == nil && user.Email.Valid && user.EmailVerifiedAt.Valid {
+		auditEvent := auditEventParams(req, user.ID, auditPasswordResetRequested, nil)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), passwordResetSendTimeout)
+		go func() {
+			defer cancel()
+
+			err := cfg.db.CreateAuditEvent(ctx, auditEvent)
+			if err != nil {
+				log.Printf("Unable to record audit event %s for user %s: %v", auditPasswordResetRequested, user.ID, err)
+			}
+
+			err = cfg.sendPasswordReset(ctx, user)
+			if err != nil {
+				log.Printf("Unable to send password reset for user %s: %v", user.ID, err)
+			}
+		}()
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Unable to look up user for password reset: %v", err)
+	}
+
+	api.RespondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If the account has a verified email, a reset link has been sent to it",
+	})
+}
+
+func (cfg *config) handlerResetPassword(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Required("token", params.Token)
+		v.Password("new_password", params.NewPassword)
+	}) {
+		return
+	}
+
+	userID, err := cfg.db.ConsumeAccountToken(req.Context(), database.ConsumeAccountTokenParams{
+		Now:       time.Now().UTC(),
+		TokenHash: auth.HashToken(params.Token),
+		Purpose:   tokenPurposePasswordReset,
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "Reset link is invalid or has expired", err)
+		return
+	}
+
+	hashedPassword, err := cfg.passwords.Hash(params.NewPassword)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Error hashing password", err)
+		return
+	}
+
+	err = cfg.db.UpdateUserPassword(req.Context(), database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+		UpdatedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to update password", err)
+		return
+	}
+
+	// Whoever had the old password may still be logged in, so end every existing session
+	err = cfg.revokeAllSessions(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to revoke existing sessions", err)
+		return
+	}
+
+	cfg.recordAuditEvent(req, userID, auditPasswordReset, nil)
+	cfg.recordAuditEvent(req, userID, auditTokenRevoked, map[string]any{"scope": "all_sessions"})
+
+	api.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Password has been reset, please log in again",
+	})
+}
+
+func (cfg *config) sendPasswordReset(ctx context.Context, user database.User) error {
+	token, err := cfg.issueAccountToken(ctx, user.ID, tokenPurposePasswordReset, passwordResetTokenExpiry)
+	if err != nil {
+		return err
+	}
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email.String,
+		Subject: "Reset your Steam Lens password",
+		Body: fmt.Sprintf("A password reset was requested for %s. Use the link below to choose a new password, it expires in %d minutes.\n\n%s\n\nIf you didn't ask for this you can ignore this email.\n",
+			user.Username, int(passwordResetTokenExpiry.Minutes()), cfg.accountTokenLink("reset-password", token)),
+	})
+}
+
+// Lets the old verified address know the account email is being changed, in case it wasn't the owner who asked
+func (cfg *config) sendEmailChangedNotice(ctx context.Context, user database.User, newEmail string) error {
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email.String,
+		Subject: "Your Steam Lens email is being changed",
+		Body: fmt.Sprintf("A change of the email address of %s to %s was requested, it takes effect once the new address is verified.\n\nIf you didn't do this, reset your password right away and contact an administrator.\n",
+			user.Username, newEmail),
+	})
+}
+
+// Creates a new single-use token for the purpose and invalidates any older ones, only the hash is stored
+func (cfg *config) issueAccountToken(ctx context.Context, userID uuid.UUID, purpose string, expiresIn time.Duration) (string, error) {
+	err := cfg.db.DeleteAccountTokensForUser(ctx, database.DeleteAccountTokensForUserParams{
+		UserID:  userID,
+		Purpose: purpose,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = cfg.db.CreateAccountToken(ctx, database.CreateAccountTokenParams{
+		TokenHash: auth.HashToken(token),
+		Purpose:   purpose,
+		CreatedAt: time.Now().UTC(),
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(expiresIn),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Helper function to build the frontend link that a token is emailed in
+func (cfg *config) accountTokenLink(page, token string) string {
+	query := url.Values{}
+	query.Set("token", token)
+	return strings.TrimRight(cfg.appBaseURL, "/") + "/" + page + "?" + query.Encode()
+}
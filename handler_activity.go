@@ -0,0 +1,101 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultActivityPageSize = 25
+	maxActivityPageSize     = 100
+)
+
+type activityResponse struct {
+	Events []AuditEvent `json:"events"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// Security events for the logged in user's own account, newest first
+func (cfg *config) handlerGetMyActivity(w http.ResponseWriter, req *http.Request) {
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	limit, offset, err := pageParams(req, defaultActivityPageSize, maxActivityPageSize)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "'limit' and 'offset' must be non-negative numbers", err)
+		return
+	}
+
+	events, err := cfg.db.ListAuditEventsForUser(req.Context(), database.ListAuditEventsForUserParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to get account activity", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, activityResponse{
+		Events: databaseAuditEventsToAuditEvents(events),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// Every audit event across all users, optionally filtered by userID, eventType and since (RFC 3339)
+func (cfg *config) handlerAdminListAuditEvents(w http.ResponseWriter, req *http.Request) {
+	limit, offset, err := pageParams(req, defaultAdminPageSize, maxAdminPageSize)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "'limit' and 'offset' must be non-negative numbers", err)
+		return
+	}
+
+	params := database.ListAuditEventsParams{
+		PageLimit:  int32(limit),
+		PageOffset: int32(offset),
+	}
+
+	if userIDQuery := req.URL.Query().Get("userID"); userIDQuery != "" {
+		userID, err := uuid.Parse(userIDQuery)
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "'userID' must be a valid user ID", err)
+			return
+		}
+		params.UserID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+
+	if eventType := req.URL.Query().Get("eventType"); eventType != "" {
+		params.EventType = sql.NullString{String: eventType, Valid: true}
+	}
+
+	if sinceQuery := req.URL.Query().Get("since"); sinceQuery != "" {
+		since, err := time.Parse(time.RFC3339, sinceQuery)
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "'since' must be an RFC 3339 timestamp", err)
+			return
+		}
+		params.Since = sql.NullTime{Time: since.UTC(), Valid: true}
+	}
+
+	events, err := cfg.db.ListAuditEvents(req.Context(), params)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to get audit events", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, activityResponse{
+		Events: databaseAuditEventsToAuditEvents(events),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
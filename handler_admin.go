@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+)
+
+// AdminUser is the view of a user shown to admins, it includes account state that users can't see about each other
+type AdminUser struct {
+	User
+	Role        string     `json:"role"`
+	TOTPEnabled bool       `json:"totp_enabled"`
+	DisabledAt  *time.Time `json:"disabled_at"`
+}
+
+func (cfg *config) handlerAdminListUsers(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Users  []AdminUser `json:"users"`
+		Limit  int         `json:"limit"`
+		Offset int         `json:"offset"`
+	}
+
+	limit, offset, err := pageParams(req, defaultAdminPageSize, maxAdminPageSize)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "'limit' and 'offset' must be non-negative numbers", err)
+		return
+	}
+
+	users, err := cfg.db.ListUsers(req.Context(), database.ListUsersParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to list users", err)
+		return
+	}
+
+	adminUsers := make([]AdminUser, 0, len(users))
+	for _, user := range users {
+		adminUsers = append(adminUsers, AdminUser{
+			User:        databaseUserToUser(user),
+			Role:        user.Role,
+			TOTPEnabled: user.TotpEnabled,
+			DisabledAt:  nullTimePtr(user.DisabledAt),
+		})
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Users:  adminUsers,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (cfg *config) handlerAdminDisableUser(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := adminTargetUserID(w, req)
+	if !ok {
+		return
+	}
+
+	updated, err := cfg.db.SetUserDisabled(req.Context(), database.SetUserDisabledParams{
+		ID:         targetID,
+		DisabledAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to disable user", err)
+		return
+	}
+	if updated == 0 {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", nil)
+		return
+	}
+
+	err = cfg.revokeAllSessions(req.Context(), targetID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to log out disabled user", err)
+		return
+	}
+
+	cfg.recordAuditEvent(req, targetID, auditUserDisabled, nil)
+	cfg.recordAuditEvent(req, targetID, auditTokenRevoked, map[string]any{"scope": "all_sessions"})
+
+	api.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "User disabled",
+	})
+}
+
+func (cfg *config) handlerAdminEnableUser(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := adminTargetUserID(w, req)
+	if !ok {
+		return
+	}
+
+	updated, err := cfg.db.SetUserDisabled(req.Context(), database.SetUserDisabledParams{
+		ID:         targetID,
+		DisabledAt: sql.NullTime{},
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to enable user", err)
+		return
+	}
+	if updated == 0 {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", nil)
+		return
+	}
+
+	cfg.recordAuditEvent(req, targetID, auditUserEnabled, nil)
+
+	api.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "User enabled",
+	})
+}
+
+func (cfg *config) handlerAdminLogoutUser(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := adminTargetUserID(w, req)
+	if !ok {
+		return
+	}
+
+	err := cfg.revokeAllSessions(req.Context(), targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", nil)
+		return
+	}
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to log out user", err)
+		return
+	}
+
+	cfg.recordAuditEvent(req, targetID, auditTokenRevoked, map[string]any{"scope": "all_sessions"})
+
+	api.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "User logged out of every session",
+	})
+}
+
+func (cfg *config) handlerAdminDeleteUser(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := adminTargetUserID(w, req)
+	if !ok {
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", nil)
+		return
+	}
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to look up user", err)
+		return
+	}
+
+	err = cfg.deleteAccount(req, user, auditUserDeleted)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to delete user", err)
+		return
+	}
+
+	// Login attempts are keyed by username rather than user ID so they aren't covered by the cascade
+	err = cfg.clearFailedLogins(req.Context(), user.Username)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to clear failed login attempts", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Revokes every refresh token and marks every access token issued so far as invalid, returns sql.ErrNoRows
+// when there's no user with the ID
+func (cfg *config) revokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	err := cfg.db.RevokeUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	updated, err := cfg.db.RevokeUserSessions(ctx, database.RevokeUserSessionsParams{
+		ID:                userID,
+		SessionsRevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Helper function to read the userID path parameter, admins can't target their own account
+// so they can't accidentally lock themselves out
+func adminTargetUserID(w http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	targetID, err := uuid.Parse(chi.URLParam(req, "userID"))
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return uuid.Nil, false
+	}
+
+	adminID, _ := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if targetID == adminID {
+		api.RespondWithError(w, http.StatusBadRequest, "Admins can't perform this action on their own account", nil)
+		return uuid.Nil, false
+	}
+
+	return targetID, true
+}
+
+// Helper function to read limit and offset query parameters, falling back to the default and capping at max
+func pageParams(req *http.Request, defaultLimit, maxLimit int) (int, int, error) {
+	limit := defaultLimit
+	offset := 0
+
+	if limitQuery := req.URL.Query().Get("limit"); limitQuery != "" {
+		parsed, err := strconv.Atoi(limitQuery)
+		if err != nil {
+			return 0, 0, err
+		}
+		if parsed < 0 {
+			return 0, 0, errors.New("limit can't be negative")
+		}
+		limit = min(parsed, maxLimit)
+	}
+
+	if offsetQuery := req.URL.Query().Get("offset"); offsetQuery != "" {
+		parsed, err := strconv.Atoi(offsetQuery)
+		if err != nil {
+			return 0, 0, err
+		}
+		if parsed < 0 {
+			return 0, 0, errors.New("offset can't be negative")
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/Khazz0r/steam-lens/internal/api"
 )
 
-// handler to be used in a dev environment to delete all users in the database for testing purposes
+// handler to be used in a dev environment to delete all users in the database for testing purposes, only admins can call it
 func (cfg *config) handlerDeleteAllUsers(w http.ResponseWriter, req *http.Request) {
 	if cfg.platform != "dev" {
 		w.WriteHeader(http.StatusForbidden)
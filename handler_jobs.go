@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/go-chi/chi/v5"
+)
+
+type JobRun struct {
+	Owner      string    `json:"owner"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      *string   `json:"error"`
+}
+
+type JobStatus struct {
+	Name            string  `json:"name"`
+	IntervalSeconds int     `json:"interval_seconds"`
+	LastRun         *JobRun `json:"last_run"`
+}
+
+// Every scheduled job with its most recent run from any replica
+func (cfg *config) handlerAdminListJobs(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Enabled bool        `json:"enabled"`
+		Jobs    []JobStatus `json:"jobs"`
+	}
+
+	runs, err := cfg.db.ListLatestJobRuns(req.Context())
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to list job runs", err)
+		return
+	}
+
+	latestRuns := map[string]database.JobRun{}
+	for _, run := range runs {
+		latestRuns[run.JobName] = run
+	}
+
+	jobs := []JobStatus{}
+	for _, job := range cfg.jobs.Jobs() {
+		status := JobStatus{
+			Name:            job.Name,
+			IntervalSeconds: int(job.Interval.Seconds()),
+		}
+		if run, ok := latestRuns[job.Name]; ok {
+			lastRun := databaseJobRunToJobRun(run)
+			status.LastRun = &lastRun
+		}
+		jobs = append(jobs, status)
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Enabled: cfg.jobsEnabled,
+		Jobs:    jobs,
+	})
+}
+
+func (cfg *config) handlerAdminListJobRuns(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Runs   []JobRun `json:"runs"`
+		Limit  int      `json:"limit"`
+		Offset int      `json:"offset"`
+	}
+
+	jobName := chi.URLParam(req, "jobName")
+	known := false
+	for _, job := range cfg.jobs.Jobs() {
+		known = known || job.Name == jobName
+	}
+	if !known {
+		api.RespondWithError(w, http.StatusNotFound, "Job not found", nil)
+		return
+	}
+
+	limit, offset, err := pageParams(req, defaultAdminPageSize, maxAdminPageSize)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "'limit' and 'offset' must be non-negative numbers", err)
+		return
+	}
+
+	runs, err := cfg.db.ListJobRuns(req.Context(), database.ListJobRunsParams{
+		JobName: jobName,
+		Limit:   int32(limit),
+		Offset:  int32(offset),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to list job runs", err)
+		return
+	}
+
+	result := make([]JobRun, 0, len(runs))
+	for _, run := range runs {
+		result = append(result, databaseJobRunToJobRun(run))
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Runs:   result,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func databaseJobRunToJobRun(run database.JobRun) JobRun {
+	jobRun := JobRun{
+		Owner:      run.Owner,
+		StartedAt:  run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		DurationMs: run.FinishedAt.Sub(run.StartedAt).Milliseconds(),
+	}
+	if run.Error.Valid {
+		jobRun.Error = &run.Error.String
+	}
+	return jobRun
+}
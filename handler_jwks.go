@@ -0,0 +1,13 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+)
+
+// Publishes the public keys tokens are signed with so other services can verify them on their own
+func (cfg *config) handlerJWKS(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	api.RespondWithJSON(w, http.StatusOK, cfg.jwtKeys.JWKS())
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/Khazz0r/steam-lens/internal/validate"
+	"github.com/google/uuid"
+)
+
+// Deletes only the logged in user's account, everything they own is removed by the foreign key cascades
+// and their audit events are pseudonymized
+func (cfg *config) handlerDeleteMe(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Required("password", params.Password)
+	}) {
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", err)
+		return
+	}
+
+	_, err = cfg.passwords.Check(user.HashedPassword, params.Password)
+	if err != nil {
+		api.RespondWithAppError(w, api.NewError(http.StatusUnauthorized, api.CodeInvalidCredentials, "Invalid password", err))
+		return
+	}
+
+	err = cfg.deleteAccount(req, user, auditAccountDeleted)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to delete account", err)
+		return
+	}
+
+	// Login attempts are keyed by username rather than user ID so they aren't covered by the cascade
+	err = cfg.clearFailedLogins(req.Context(), user.Username)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to clear failed login attempts", err)
+		return
+	}
+
+	clearSessionCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deletes the account and pseudonymizes its audit events in one transaction, the foreign key cascades remove
+// everything else it owns. Playtime samples and snapshots of the account's Steam ID aren't linked to the account,
+// so they're deleted by Steam ID unless another account still uses it. The deletion event is written before
+// pseudonymizing so it's stripped the same way
+func (cfg *config) deleteAccount(req *http.Request, user database.User, eventType string) error {
+	tx, err := cfg.sqlDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	_, err = qtx.DeleteUser(req.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = qtx.DeleteUnclaimedPlaytimeSamples(req.Context(), user.SteamID)
+	if err != nil {
+		return err
+	}
+
+	err = qtx.DeleteUnclaimedLibrarySnapshots(req.Context(), user.SteamID)
+	if err != nil {
+		return err
+	}
+
+	err = qtx.CreateAuditEvent(req.Context(), auditEventParams(req, user.ID, eventType, nil))
+	if err != nil {
+		return err
+	}
+
+	_, err = qtx.PseudonymizeAuditEvents(req.Context(), database.PseudonymizeAuditEventsParams{
+		UserID:   user.ID,
+		Username: user.Username,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type exportedSession struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+type exportedRecoveryCode struct {
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type exportedAccountToken struct {
+	Purpose   string     `json:"purpose"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type exportedLoginAttempts struct {
+	FailedCount  int32      `json:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+type exportedPlaytimeSample struct {
+	AppID           int32     `json:"app_id"`
+	Name            string    `json:"name"`
+	SampledAt       time.Time `json:"sampled_at"`
+	PlaytimeForever int32     `json:"playtime_forever"`
+	Playtime2Weeks  int32     `json:"playtime_2weeks"`
+}
+
+// userExport is everything stored about a user. Secrets such as the password hash, the TOTP secret,
+// and token values are left out, only the fact that they exist and when they were used is included
+type userExport struct {
+	ExportedAt        time.Time                `json:"exported_at"`
+	User              User                     `json:"user"`
+	Role              string                   `json:"role"`
+	TOTPEnabled       bool                     `json:"totp_enabled"`
+	DisabledAt        *time.Time               `json:"disabled_at"`
+	SessionsRevokedAt *time.Time               `json:"sessions_revoked_at"`
+	Sessions          []exportedSession        `json:"sessions"`
+	RecoveryCodes     []exportedRecoveryCode   `json:"recovery_codes"`
+	AccountTokens     []exportedAccountToken   `json:"account_tokens"`
+	LoginAttempts     *exportedLoginAttempts   `json:"login_attempts"`
+	Activity          []AuditEvent             `json:"activity"`
+	Squads            []Squad                  `json:"squads"`
+	Snapshots         []LibrarySnapshot        `json:"snapshots_taken"`
+	LibrarySnapshots  []LibrarySnapshot        `json:"library_snapshots"`
+	PlaytimeSamples   []exportedPlaytimeSample `json:"playtime_samples"`
+}
+
+// Returns a JSON bundle of everything stored about the logged in user as a file download
+func (cfg *config) handlerExportMe(w http.ResponseWriter, req *http.Request) {
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	export, err := cfg.buildUserExport(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to export account data", err)
+		return
+	}
+
+	cfg.recordAuditEvent(req, userID, auditDataExported, nil)
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="steam-lens-export-%s.json"`, userID))
+	api.RespondWithJSON(w, http.StatusOK, export)
+}
+
+func (cfg *config) buildUserExport(ctx context.Context, userID uuid.UUID) (userExport, error) {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+
+	export := userExport{
+		ExportedAt: time.Now().UTC(),
+		User: User{
+			ID:            user.ID,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+			Username:      user.Username,
+			SteamID:       user.SteamID,
+			Email:         user.Email.String,
+			EmailVerified: user.EmailVerifiedAt.Valid,
+			PendingEmail:  user.PendingEmail.String,
+		},
+		Role:              user.Role,
+		TOTPEnabled:       user.TotpEnabled,
+		DisabledAt:        nullTimePtr(user.DisabledAt),
+		SessionsRevokedAt: nullTimePtr(user.SessionsRevokedAt),
+		Sessions:          []exportedSession{},
+		RecoveryCodes:     []exportedRecoveryCode{},
+		AccountTokens:     []exportedAccountToken{},
+	}
+
+	refreshTokens, err := cfg.db.ListRefreshTokensForUser(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+	for _, token := range refreshTokens {
+		export.Sessions = append(export.Sessions, exportedSession{
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			RevokedAt: nullTimePtr(token.RevokedAt),
+		})
+	}
+
+	recoveryCodes, err := cfg.db.ListRecoveryCodesForUser(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+	for _, code := range recoveryCodes {
+		export.RecoveryCodes = append(export.RecoveryCodes, exportedRecoveryCode{
+			CreatedAt: code.CreatedAt,
+			UsedAt:    nullTimePtr(code.UsedAt),
+		})
+	}
+
+	accountTokens, err := cfg.db.ListAccountTokensForUser(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+	for _, token := range accountTokens {
+		export.AccountTokens = append(export.AccountTokens, exportedAccountToken{
+			Purpose:   token.Purpose,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			UsedAt:    nullTimePtr(token.UsedAt),
+		})
+	}
+
+	attempt, err := cfg.db.GetLoginAttempt(ctx, database.GetLoginAttemptParams{
+		Scope:      loginScopeUsername,
+		Identifier: user.Username,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return userExport{}, err
+	}
+	if err == nil {
+		export.LoginAttempts = &exportedLoginAttempts{
+			FailedCount:  attempt.FailedCount,
+			LastFailedAt: attempt.LastFailedAt,
+			LockedUntil:  nullTimePtr(attempt.LockedUntil),
+		}
+	}
+
+	events, err := cfg.db.ListAuditEventsForUser(ctx, database.ListAuditEventsForUserParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit:  math.MaxInt32,
+		Offset: 0,
+	})
+	if err != nil {
+		return userExport{}, err
+	}
+	export.Activity = databaseAuditEventsToAuditEvents(events)
+
+	export.Squads, err = cfg.listSquads(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+
+	snapshots, err := cfg.db.ListLibrarySnapshotsCreatedBy(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return userExport{}, err
+	}
+	export.Snapshots = []LibrarySnapshot{}
+	for _, snapshot := range snapshots {
+		export.Snapshots = append(export.Snapshots, databaseSnapshotToSnapshot(snapshot, nil))
+	}
+
+	// Snapshots and samples of the user's own Steam ID, whoever or whatever took them
+	librarySnapshots, err := cfg.db.ListLibrarySnapshots(ctx, database.ListLibrarySnapshotsParams{
+		SteamID: user.SteamID,
+		Limit:   math.MaxInt32,
+		Offset:  0,
+	})
+	if err != nil {
+		return userExport{}, err
+	}
+	export.LibrarySnapshots = []LibrarySnapshot{}
+	for _, listed := range librarySnapshots {
+		snapshot, games, err := cfg.loadLibrarySnapshot(ctx, listed.ID)
+		if err != nil {
+			return userExport{}, err
+		}
+		export.LibrarySnapshots = append(export.LibrarySnapshots, databaseSnapshotToSnapshot(snapshot, games))
+	}
+
+	samples, err := cfg.db.ListPlaytimeSamples(ctx, database.ListPlaytimeSamplesParams{
+		SteamID: user.SteamID,
+		After:   time.Time{},
+		Until:   export.ExportedAt,
+	})
+	if err != nil {
+		return userExport{}, err
+	}
+	export.PlaytimeSamples = []exportedPlaytimeSample{}
+	for _, sample := range samples {
+		export.PlaytimeSamples = append(export.PlaytimeSamples, exportedPlaytimeSample{
+			AppID:           sample.AppID,
+			Name:            sample.Name,
+			SampledAt:       sample.SampledAt,
+			PlaytimeForever: sample.PlaytimeForever,
+			Playtime2Weeks:  sample.Playtime2weeks,
+		})
+	}
+
+	return export, nil
+}
+
+// Helper function to turn a nullable time into a pointer so it marshals as null when it isn't set
+func nullTimePtr(nullTime sql.NullTime) *time.Time {
+	if !nullTime.Valid {
+		return nil
+	}
+	return &nullTime.Time
+}
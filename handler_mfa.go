@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/auth"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/Khazz0r/steam-lens/internal/validate"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer            = "Steam Lens"
+	mfaPendingTokenExpiry = 5 * time.Minute
+	recoveryCodeCount     = 10
+)
+
+type mfaPendingResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// Starts two-factor enrollment by generating a new secret, it isn't enforced until confirmed with a first code
+func (cfg *config) handlerMFAEnroll(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", err)
+		return
+	}
+
+	if user.TotpEnabled {
+		api.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to generate two-factor secret", err)
+		return
+	}
+
+	err = cfg.db.SetTOTPSecret(req.Context(), database.SetTOTPSecretParams{
+		ID:         userID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to save two-factor secret", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// Confirms enrollment with a first code from the authenticator app, enables two-factor and hands out recovery codes
+func (cfg *config) handlerMFAConfirm(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Required("code", params.Code)
+	}) {
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", err)
+		return
+	}
+
+	if user.TotpEnabled {
+		api.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		api.RespondWithError(w, http.StatusBadRequest, "Two-factor enrollment has not been started", nil)
+		return
+	}
+
+	verified, err := cfg.useTOTPCode(req.Context(), user, params.Code)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to check two-factor code", err)
+		return
+	}
+	if !verified {
+		api.RespondWithAppError(w, api.NewError(http.StatusUnauthorized, api.CodeInvalidCredentials, "Invalid two-factor code", nil))
+		return
+	}
+
+	recoveryCodes, err := cfg.replaceRecoveryCodes(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to create recovery codes", err)
+		return
+	}
+
+	err = cfg.db.EnableTOTP(req.Context(), database.EnableTOTPParams{
+		ID:        userID,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to enable two-factor authentication", err)
+		return
+	}
+
+	cfg.recordAuditEvent(req, userID, auditMFAEnabled, nil)
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// Turns two-factor off, requires the account password so a stolen session alone can't remove it
+func (cfg *config) handlerMFADisable(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Required("password", params.Password)
+	}) {
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", err)
+		return
+	}
+
+	_, err = cfg.passwords.Check(user.HashedPassword, params.Password)
+	if err != nil {
+		api.RespondWithAppError(w, api.NewError(http.StatusUnauthorized, api.CodeInvalidCredentials, "Invalid password", err))
+		return
+	}
+
+	err = cfg.db.DisableTOTP(req.Context(), database.DisableTOTPParams{
+		ID:        userID,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to disable two-factor authentication", err)
+		return
+	}
+
+	err = cfg.db.DeleteRecoveryCodes(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to delete recovery codes", err)
+		return
+	}
+
+	cfg.recordAuditEvent(req, userID, auditMFADisabled, nil)
+
+	api.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// Second login step, trades the pending token from handlerLogin plus a TOTP or recovery code for a real session
+func (cfg *config) handlerLoginMFA(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Required("mfa_token", params.MFAToken)
+		v.Check(strings.TrimSpace(params.Code) != "" || strings.TrimSpace(params.RecoveryCode) != "", "code", "a code or recovery_code is required")
+	}) {
+		return
+	}
+
+	userID, err := cfg.jwtKeys.ValidateMFAPendingToken(params.MFAToken)
+	if err != nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Two-factor login expired, please log in again", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Two-factor login expired, please log in again", err)
+		return
+	}
+	if !user.TotpEnabled || !user.TotpSecret.Valid {
+		api.RespondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled for this account", nil)
+		return
+	}
+
+	// Codes are only 6 digits so they get the same throttling as passwords
+	keys := loginKeys(user.Username, clientIP(req))
+
+	lockedUntil, err := cfg.loginLockedUntil(req.Context(), keys)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to check login attempts", err)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		cfg.recordAuditEvent(req, userID, auditLoginLocked, map[string]any{"username": user.Username, "step": "mfa"})
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(lockedUntil).Seconds())+1))
+		api.RespondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, please try again later", nil)
+		return
+	}
+
+	verified := false
+	if strings.TrimSpace(params.RecoveryCode) != "" {
+		used, err := cfg.db.UseRecoveryCode(req.Context(), database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(params.RecoveryCode),
+			UsedAt:   sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		})
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "Unable to check recovery code", err)
+			return
+		}
+		verified = used == 1
+		if verified {
+			cfg.recordAuditEvent(req, userID, auditRecoveryCodeUsed, nil)
+		}
+	} else {
+		verified, err = cfg.useTOTPCode(req.Context(), user, params.Code)
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "Unable to check two-factor code", err)
+			return
+		}
+	}
+
+	if !verified {
+		cfg.recordAuditEvent(req, userID, auditLoginFailed, map[string]any{"username": user.Username, "reason": "invalid_mfa_code"})
+
+		delay, err := cfg.recordFailedLogin(req.Context(), keys)
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "Unable to record failed login attempt", err)
+			return
+		}
+		sleepWithContext(req.Context(), delay)
+
+		api.RespondWithAppError(w, api.NewError(http.StatusUnauthorized, api.CodeInvalidCredentials, "Invalid two-factor code", nil))
+		return
+	}
+
+	err = cfg.clearFailedLogins(req.Context(), user.Username)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to clear failed login attempts", err)
+		return
+	}
+
+	cfg.respondWithSession(w, req, user)
+}
+
+// Checks a TOTP code and records its period as used, a code that was already accepted once is rejected.
+// The update only succeeds for a newer period so two requests racing with the same code can't both get in
+func (cfg *config) useTOTPCode(ctx context.Context, user database.User, code string) (bool, error) {
+	counter, valid := auth.ValidateTOTP(user.TotpSecret.String, code, time.Now(), user.TotpLastCounter)
+	if !valid {
+		return false, nil
+	}
+
+	updated, err := cfg.db.UseTOTPCounter(ctx, database.UseTOTPCounterParams{
+		Counter: counter,
+		ID:      user.ID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return updated == 1, nil
+}
+
+// Helper function to throw away any old recovery codes and store hashes of a fresh set, returns the plain codes
+func (cfg *config) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	err := cfg.db.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		err = cfg.db.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UserID:    userID,
+			CodeHash:  auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
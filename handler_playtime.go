@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/Khazz0r/steam-lens/internal/validate"
+)
+
+const (
+	defaultPlaytimeWeeks  = 12
+	maxPlaytimeWeeks      = 52
+	defaultPlaytimeMonths = 6
+	maxPlaytimeMonths     = 24
+	defaultTopGamesDays   = 30
+	maxTopGamesDays       = 365
+	defaultTopGamesLimit  = 10
+	maxTopGamesLimit      = 100
+)
+
+// Hours played per game in each of the last few weeks, built from the playtime samples taken by the sample_playtime job
+func (cfg *config) handlerWeeklyPlaytime(w http.ResponseWriter, req *http.Request) {
+	cfg.respondWithPlaytimePeriods(w, req, "weeks", defaultPlaytimeWeeks, maxPlaytimeWeeks, api.WeekWindows)
+}
+
+func (cfg *config) handlerMonthlyPlaytime(w http.ResponseWriter, req *http.Request) {
+	cfg.respondWithPlaytimePeriods(w, req, "months", defaultPlaytimeMonths, maxPlaytimeMonths, api.MonthWindows)
+}
+
+func (cfg *config) respondWithPlaytimePeriods(w http.ResponseWriter, req *http.Request, countParam string, defaultCount, maxCount int, windowsFor func(time.Time, int) [][2]time.Time) {
+	type response struct {
+		SteamID string               `json:"steam_id"`
+		Tracked bool                 `json:"tracked"`
+		Periods []api.PlaytimePeriod `json:"periods"`
+	}
+
+	steamID, ok := steamIDQueryParam(w, req)
+	if !ok {
+		return
+	}
+
+	count, err := boundedQueryParam(req, countParam, defaultCount, maxCount)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	windows := windowsFor(time.Now(), count)
+	samples, err := cfg.loadPlaytimeSamples(req.Context(), steamID, windows[0][0], windows[len(windows)-1][1])
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to get playtime history", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		SteamID: steamID,
+		Tracked: len(samples) > 0,
+		Periods: api.PlaytimePeriods(samples, windows),
+	})
+}
+
+// The most played games over the last few days
+func (cfg *config) handlerTopPlaytimeGames(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		SteamID string             `json:"steam_id"`
+		Tracked bool               `json:"tracked"`
+		From    time.Time          `json:"from"`
+		To      time.Time          `json:"to"`
+		Games   []api.GamePlaytime `json:"games"`
+	}
+
+	steamID, ok := steamIDQueryParam(w, req)
+	if !ok {
+		return
+	}
+
+	days, err := boundedQueryParam(req, "days", defaultTopGamesDays, maxTopGamesDays)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	limit, err := boundedQueryParam(req, "limit", defaultTopGamesLimit, maxTopGamesLimit)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -days)
+	samples, err := cfg.loadPlaytimeSamples(req.Context(), steamID, from, to)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to get playtime history", err)
+		return
+	}
+
+	games := api.PlaytimeInWindow(samples, from, to)
+	if len(games) > limit {
+		games = games[:limit]
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		SteamID: steamID,
+		Tracked: len(samples) > 0,
+		From:    from,
+		To:      to,
+		Games:   games,
+	})
+}
+
+// Ranks a Steam ID and their friends by how much they played one game this week or month, only players whose
+// playtime is sampled (registered users and their friends) have data
+func (cfg *config) handlerFriendsPlaytime(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		SteamID string               `json:"steam_id"`
+		AppID   int                  `json:"app_id"`
+		Period  string               `json:"period"`
+		From    time.Time            `json:"from"`
+		To      time.Time            `json:"to"`
+		Players []api.PlayerPlaytime `json:"players"`
+	}
+
+	steamID, ok := steamIDQueryParam(w, req)
+	if !ok {
+		return
+	}
+
+	appID, err := strconv.Atoi(req.URL.Query().Get("appID"))
+	if err != nil || appID <= 0 {
+		api.RespondWithError(w, http.StatusBadRequest, "'appID' parameter must be a Steam app ID", err)
+		return
+	}
+
+	period := req.URL.Query().Get("period")
+	var window [2]time.Time
+	switch period {
+	case "week":
+		window = api.WeekWindows(time.Now(), 1)[0]
+	case "month", "":
+		period = "month"
+		window = api.MonthWindows(time.Now(), 1)[0]
+	default:
+		api.RespondWithError(w, http.StatusBadRequest, "'period' parameter must be week or month", nil)
+		return
+	}
+
+	friendList, err := cfg.steamAPI.GetFriendList(steamID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadGateway, "Unable to get friend list from Steam", err)
+		return
+	}
+
+	steamIDs := []string{steamID}
+	for _, friend := range friendList.Friends {
+		steamIDs = append(steamIDs, friend.SteamID)
+	}
+
+	baseline, err := cfg.db.ListLatestAppPlaytimeSamples(req.Context(), database.ListLatestAppPlaytimeSamplesParams{
+		SteamIds: steamIDs,
+		AppID:    int32(appID),
+		Before:   window[0],
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to get playtime history", err)
+		return
+	}
+	rows, err := cfg.db.ListAppPlaytimeSamples(req.Context(), database.ListAppPlaytimeSamplesParams{
+		SteamIds: steamIDs,
+		AppID:    int32(appID),
+		After:    window[0],
+		Until:    window[1],
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to get playtime history", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		SteamID: steamID,
+		AppID:   appID,
+		Period:  period,
+		From:    window[0],
+		To:      window[1],
+		Players: api.RankPlayersByPlaytime(databaseSamplesToSamples(append(baseline, rows...)), steamIDs, window[0], window[1]),
+	})
+}
+
+// Loads the samples needed to work out playtime between from and to, the latest sample of each game before from
+// is included as the starting point
+func (cfg *config) loadPlaytimeSamples(ctx context.Context, steamID string, from, to time.Time) ([]api.PlaytimeSample, error) {
+	baseline, err := cfg.db.ListLatestPlaytimeSamples(ctx, database.ListLatestPlaytimeSamplesParams{
+		SteamID: steamID,
+		Before:  from,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := cfg.db.ListPlaytimeSamples(ctx, database.ListPlaytimeSamplesParams{
+		SteamID: steamID,
+		After:   from,
+		Until:   to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return databaseSamplesToSamples(append(baseline, rows...)), nil
+}
+
+// Stores the current playtime of every game a Steam ID owns or recently played. A game only gets a new sample
+// when its total changed since the last one, so idle libraries don't grow the table
+func (cfg *config) samplePlaytime(ctx context.Context, steamID string) error {
+	ownedGames, err := cfg.steamAPI.FetchOwnedGames(steamID)
+	if err != nil {
+		return err
+	}
+	recentGames, err := cfg.steamAPI.FetchRecentlyPlayedGames(steamID)
+	if err != nil {
+		return err
+	}
+
+	games := map[int]api.Game{}
+	appIDs := []int{}
+	for _, game := range append(ownedGames.Games, recentGames.Games...) {
+		existing, found := games[game.AppID]
+		if !found {
+			appIDs = append(appIDs, game.AppID)
+		}
+		if !found || game.PlaytimeForever > existing.PlaytimeForever {
+			games[game.AppID] = game
+		}
+	}
+
+	sampledAt := time.Now().UTC()
+	latest, err := cfg.db.ListLatestPlaytimeSamples(ctx, database.ListLatestPlaytimeSamplesParams{
+		SteamID: steamID,
+		Before:  sampledAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	previous := map[int]int{}
+	for _, sample := range latest {
+		previous[int(sample.AppID)] = int(sample.PlaytimeForever)
+	}
+
+	params := database.AddPlaytimeSamplesParams{
+		SteamID:   steamID,
+		SampledAt: sampledAt,
+	}
+	for _, appID := range appIDs {
+		game := games[appID]
+		if playtime, found := previous[appID]; found && playtime == game.PlaytimeForever {
+			continue
+		}
+		params.AppIds = append(params.AppIds, int32(appID))
+		params.Names = append(params.Names, game.Name)
+		params.PlaytimesForever = append(params.PlaytimesForever, int32(game.PlaytimeForever))
+		params.Playtimes2weeks = append(params.Playtimes2weeks, int32(game.Playtime2Weeks))
+	}
+
+	if len(params.AppIds) == 0 {
+		return nil
+	}
+	return cfg.db.AddPlaytimeSamples(ctx, params)
+}
+
+func databaseSamplesToSamples(rows []database.PlaytimeSample) []api.PlaytimeSample {
+	samples := make([]api.PlaytimeSample, 0, len(rows))
+	for _, row := range rows {
+		samples = append(samples, api.PlaytimeSample{
+			SteamID:         row.SteamID,
+			AppID:           int(row.AppID),
+			Name:            row.Name,
+			SampledAt:       row.SampledAt,
+			PlaytimeForever: int(row.PlaytimeForever),
+			Playtime2Weeks:  int(row.Playtime2weeks),
+		})
+	}
+	return samples
+}
+
+func steamIDQueryParam(w http.ResponseWriter, req *http.Request) (string, bool) {
+	steamID := req.URL.Query().Get("steamID")
+	if !validate.IsSteamID64(steamID) {
+		api.RespondWithError(w, http.StatusBadRequest, "'steamID' parameter must be a SteamID64", nil)
+		return "", false
+	}
+	return steamID, true
+}
+
+// Helper function to read a positive number query parameter, falling back to the default and capping at max
+func boundedQueryParam(req *http.Request, name string, defaultValue, maxValue int) (int, error) {
+	query := req.URL.Query().Get(name)
+	if query == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.Atoi(query)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("'%s' parameter must be a positive number", name)
+	}
+	return min(parsed, maxValue), nil
+}
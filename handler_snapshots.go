@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/Khazz0r/steam-lens/internal/validate"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	snapshotSourceManual    = "manual"
+	snapshotSourceScheduled = "scheduled"
+
+	// Manual snapshots of the same Steam ID closer together than this would only repeat the same data
+	minManualSnapshotInterval = 10 * time.Minute
+
+	defaultSnapshotPageSize = 50
+	maxSnapshotPageSize     = 200
+)
+
+var errLibraryNotVisible = errors.New("library is private or empty")
+
+type LibrarySnapshot struct {
+	ID        uuid.UUID  `json:"id"`
+	SteamID   string     `json:"steam_id"`
+	TakenAt   time.Time  `json:"taken_at"`
+	GameCount int        `json:"game_count"`
+	Source    string     `json:"source"`
+	Games     []api.Game `json:"games,omitempty"`
+}
+
+// Records the current library of a Steam ID so it can be compared with later ones
+func (cfg *config) handlerCreateSnapshot(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		SteamID string `json:"steam_id"`
+	}
+	type response struct {
+		Snapshot LibrarySnapshot `json:"snapshot"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	params := parameters{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.SteamID64("steam_id", params.SteamID)
+	}) {
+		return
+	}
+
+	latest, err := cfg.db.GetLatestLibrarySnapshot(req.Context(), params.SteamID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to check existing snapshots", err)
+		return
+	}
+	if err == nil && time.Since(latest.TakenAt) < minManualSnapshotInterval {
+		retryAfter := minManualSnapshotInterval - time.Since(latest.TakenAt)
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		api.RespondWithError(w, http.StatusTooManyRequests, "A snapshot of this Steam ID was taken recently, please try again later", nil)
+		return
+	}
+
+	snapshot, games, err := cfg.takeLibrarySnapshot(req.Context(), params.SteamID, snapshotSourceManual, uuid.NullUUID{UUID: userID, Valid: true})
+	if errors.Is(err, errLibraryNotVisible) {
+		api.RespondWithError(w, http.StatusUnprocessableEntity, "This library is private or has no games", err)
+		return
+	}
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadGateway, "Unable to take library snapshot", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusCreated, response{
+		Snapshot: databaseSnapshotToSnapshot(snapshot, games),
+	})
+}
+
+func (cfg *config) handlerListSnapshots(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Snapshots []LibrarySnapshot `json:"snapshots"`
+	}
+
+	steamID, ok := steamIDQueryParam(w, req)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := pageParams(req, defaultSnapshotPageSize, maxSnapshotPageSize)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "'limit' and 'offset' must be non-negative numbers", err)
+		return
+	}
+
+	snapshots, err := cfg.db.ListLibrarySnapshots(req.Context(), database.ListLibrarySnapshotsParams{
+		SteamID: steamID,
+		Limit:   int32(limit),
+		Offset:  int32(offset),
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to list snapshots", err)
+		return
+	}
+
+	result := []LibrarySnapshot{}
+	for _, snapshot := range snapshots {
+		result = append(result, databaseSnapshotToSnapshot(snapshot, nil))
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Snapshots: result,
+	})
+}
+
+func (cfg *config) handlerGetSnapshot(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Snapshot LibrarySnapshot `json:"snapshot"`
+	}
+
+	snapshotID, err := uuid.Parse(chi.URLParam(req, "snapshotID"))
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "Invalid snapshot ID", err)
+		return
+	}
+
+	snapshot, games, err := cfg.loadLibrarySnapshot(req.Context(), snapshotID)
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to get snapshot"))
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Snapshot: databaseSnapshotToSnapshot(snapshot, games),
+	})
+}
+
+// Compares two snapshots of the same Steam ID, from is the older one
+func (cfg *config) handlerDiffSnapshots(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		From LibrarySnapshot `json:"from"`
+		To   LibrarySnapshot `json:"to"`
+		api.LibraryDiff
+	}
+
+	fromID, err := uuid.Parse(req.URL.Query().Get("from"))
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "'from' parameter must be a snapshot ID", err)
+		return
+	}
+	toID, err := uuid.Parse(req.URL.Query().Get("to"))
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "'to' parameter must be a snapshot ID", err)
+		return
+	}
+
+	fromSnapshot, fromGames, err := cfg.loadLibrarySnapshot(req.Context(), fromID)
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to get 'from' snapshot"))
+		return
+	}
+	toSnapshot, toGames, err := cfg.loadLibrarySnapshot(req.Context(), toID)
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to get 'to' snapshot"))
+		return
+	}
+
+	if fromSnapshot.SteamID != toSnapshot.SteamID {
+		api.RespondWithError(w, http.StatusBadRequest, "Both snapshots must be of the same Steam ID", nil)
+		return
+	}
+	if !fromSnapshot.TakenAt.Before(toSnapshot.TakenAt) {
+		api.RespondWithError(w, http.StatusBadRequest, "The 'from' snapshot must be older than the 'to' snapshot", nil)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		From:        databaseSnapshotToSnapshot(fromSnapshot, nil),
+		To:          databaseSnapshotToSnapshot(toSnapshot, nil),
+		LibraryDiff: api.DiffLibraries(fromGames, toGames),
+	})
+}
+
+// Fetches the library fresh from Steam and stores it, an empty library isn't stored since a private
+// profile looks the same and would show up as every game being removed
+func (cfg *config) takeLibrarySnapshot(ctx context.Context, steamID, source string, createdBy uuid.NullUUID) (database.LibrarySnapshot, []api.Game, error) {
+	ownedGames, err := cfg.steamAPI.FetchOwnedGames(steamID)
+	if err != nil {
+		return database.LibrarySnapshot{}, nil, err
+	}
+	if len(ownedGames.Games) == 0 {
+		return database.LibrarySnapshot{}, nil, errLibraryNotVisible
+	}
+
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.LibrarySnapshot{}, nil, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	snapshot, err := qtx.CreateLibrarySnapshot(ctx, database.CreateLibrarySnapshotParams{
+		ID:        uuid.New(),
+		SteamID:   steamID,
+		TakenAt:   time.Now().UTC(),
+		GameCount: int32(len(ownedGames.Games)),
+		Source:    source,
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return database.LibrarySnapshot{}, nil, err
+	}
+
+	params := database.AddLibrarySnapshotGamesParams{SnapshotID: snapshot.ID}
+	seen := map[int]bool{}
+	for _, game := range ownedGames.Games {
+		if seen[game.AppID] {
+			continue
+		}
+		seen[game.AppID] = true
+		params.AppIds = append(params.AppIds, int32(game.AppID))
+		params.Names = append(params.Names, game.Name)
+		params.Playtimes = append(params.Playtimes, int32(game.PlaytimeForever))
+	}
+
+	err = qtx.AddLibrarySnapshotGames(ctx, params)
+	if err != nil {
+		return database.LibrarySnapshot{}, nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return database.LibrarySnapshot{}, nil, err
+	}
+
+	return snapshot, ownedGames.Games, nil
+}
+
+func (cfg *config) loadLibrarySnapshot(ctx context.Context, snapshotID uuid.UUID) (database.LibrarySnapshot, []api.Game, error) {
+	snapshot, err := cfg.db.GetLibrarySnapshot(ctx, snapshotID)
+	if err != nil {
+		return database.LibrarySnapshot{}, nil, err
+	}
+
+	rows, err := cfg.db.ListLibrarySnapshotGames(ctx, snapshotID)
+	if err != nil {
+		return database.LibrarySnapshot{}, nil, err
+	}
+
+	games := []api.Game{}
+	for _, row := range rows {
+		games = append(games, api.Game{
+			AppID:           int(row.AppID),
+			Name:            row.Name,
+			PlaytimeForever: int(row.PlaytimeForever),
+		})
+	}
+
+	return snapshot, games, nil
+}
+
+func databaseSnapshotToSnapshot(snapshot database.LibrarySnapshot, games []api.Game) LibrarySnapshot {
+	return LibrarySnapshot{
+		ID:        snapshot.ID,
+		SteamID:   snapshot.SteamID,
+		TakenAt:   snapshot.TakenAt,
+		GameCount: int(snapshot.GameCount),
+		Source:    snapshot.Source,
+		Games:     games,
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/Khazz0r/steam-lens/internal/validate"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	maxSquadsPerUser   = 20
+	minSquadMembers    = 2
+	maxSquadMembers    = 16
+	maxSquadNameLength = 64
+)
+
+type Squad struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	SteamIDs  []string  `json:"steam_ids"`
+}
+
+type squadParams struct {
+	Name     string   `json:"name"`
+	SteamIDs []string `json:"steam_ids"`
+}
+
+// Same rules for creating and replacing a squad
+func (params *squadParams) rules(v *validate.Validator) {
+	params.Name = strings.TrimSpace(params.Name)
+	v.Required("name", params.Name)
+	v.Check(utf8.RuneCountInString(params.Name) <= maxSquadNameLength, "name", "must be at most 64 characters")
+	v.SteamIDList("steam_ids", params.SteamIDs, minSquadMembers, maxSquadMembers)
+}
+
+func (cfg *config) handlerListSquads(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Squads []Squad `json:"squads"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	squads, err := cfg.listSquads(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to get squads", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Squads: squads,
+	})
+}
+
+func (cfg *config) handlerCreateSquad(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Squad `json:"squad"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	params := squadParams{}
+	if !decodeAndValidate(w, req, &params, params.rules) {
+		return
+	}
+
+	tx, err := cfg.sqlDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	count, err := qtx.CountSquadsForUser(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to count squads", err)
+		return
+	}
+	if count >= maxSquadsPerUser {
+		api.RespondWithError(w, http.StatusConflict, "You already have the maximum of 20 squads", nil)
+		return
+	}
+
+	squad, err := qtx.CreateSquad(req.Context(), database.CreateSquadParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    userID,
+		Name:      params.Name,
+	})
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to create squad"))
+		return
+	}
+
+	err = addSquadMembers(req.Context(), qtx, squad.ID, params.SteamIDs)
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to add squad members"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to create squad", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusCreated, response{
+		Squad: databaseSquadToSquad(squad, params.SteamIDs),
+	})
+}
+
+func (cfg *config) handlerGetSquad(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Squad `json:"squad"`
+	}
+
+	squad, steamIDs, ok := cfg.squadFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Squad: databaseSquadToSquad(squad, steamIDs),
+	})
+}
+
+// Replaces the squad's name and members, the members are saved in the order they're sent
+func (cfg *config) handlerUpdateSquad(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Squad `json:"squad"`
+	}
+
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	squadID, ok := squadIDParam(w, req)
+	if !ok {
+		return
+	}
+
+	params := squadParams{}
+	if !decodeAndValidate(w, req, &params, params.rules) {
+		return
+	}
+
+	tx, err := cfg.sqlDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	squad, err := qtx.RenameSquad(req.Context(), database.RenameSquadParams{
+		ID:        squadID,
+		UserID:    userID,
+		Name:      params.Name,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to update squad"))
+		return
+	}
+
+	err = qtx.DeleteSquadMembers(req.Context(), squad.ID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to update squad members", err)
+		return
+	}
+
+	err = addSquadMembers(req.Context(), qtx, squad.ID, params.SteamIDs)
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to update squad members"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to update squad", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Squad: databaseSquadToSquad(squad, params.SteamIDs),
+	})
+}
+
+func (cfg *config) handlerDeleteSquad(w http.ResponseWriter, req *http.Request) {
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return
+	}
+
+	squadID, ok := squadIDParam(w, req)
+	if !ok {
+		return
+	}
+
+	deleted, err := cfg.db.DeleteSquad(req.Context(), database.DeleteSquadParams{
+		ID:     squadID,
+		UserID: userID,
+	})
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to delete squad", err)
+		return
+	}
+	if deleted == 0 {
+		api.RespondWithError(w, http.StatusNotFound, "Squad not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Games every member of the squad owns plus how much of the squad's combined library each member has
+func (cfg *config) handlerSquadGames(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Squad `json:"squad"`
+		api.SquadGames
+	}
+
+	squad, steamIDs, ok := cfg.squadFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	squadGames, err := cfg.steamAPI.CompareSquadGames(steamIDs)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadGateway, "Unable to get squad games from Steam", err)
+		return
+	}
+
+	api.RespondWithJSON(w, http.StatusOK, response{
+		Squad:      databaseSquadToSquad(squad, steamIDs),
+		SquadGames: squadGames,
+	})
+}
+
+// Helper function to load the squad named in the path, only squads owned by the logged in user are found
+func (cfg *config) squadFromRequest(w http.ResponseWriter, req *http.Request) (database.Squad, []string, bool) {
+	userID, exists := req.Context().Value(userIDContextKey).(uuid.UUID)
+	if !exists || userID == uuid.Nil {
+		api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to perform this action", nil)
+		return database.Squad{}, nil, false
+	}
+
+	squadID, ok := squadIDParam(w, req)
+	if !ok {
+		return database.Squad{}, nil, false
+	}
+
+	squad, err := cfg.db.GetSquadForUser(req.Context(), database.GetSquadForUserParams{
+		ID:     squadID,
+		UserID: userID,
+	})
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to get squad"))
+		return database.Squad{}, nil, false
+	}
+
+	members, err := cfg.db.ListSquadMembers(req.Context(), squad.ID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to get squad members", err)
+		return database.Squad{}, nil, false
+	}
+
+	steamIDs := []string{}
+	for _, member := range members {
+		steamIDs = append(steamIDs, member.SteamID)
+	}
+
+	return squad, steamIDs, true
+}
+
+func squadIDParam(w http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	squadID, err := uuid.Parse(chi.URLParam(req, "squadID"))
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "Invalid squad ID", err)
+		return uuid.Nil, false
+	}
+	return squadID, true
+}
+
+// Lists every squad of a user with their members, used by the list endpoint and the data export
+func (cfg *config) listSquads(ctx context.Context, userID uuid.UUID) ([]Squad, error) {
+	squads, err := cfg.db.ListSquadsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := cfg.db.ListSquadMembersForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	steamIDsBySquad := map[uuid.UUID][]string{}
+	for _, member := range members {
+		steamIDsBySquad[member.SquadID] = append(steamIDsBySquad[member.SquadID], member.SteamID)
+	}
+
+	result := []Squad{}
+	for _, squad := range squads {
+		result = append(result, databaseSquadToSquad(squad, steamIDsBySquad[squad.ID]))
+	}
+
+	return result, nil
+}
+
+func addSquadMembers(ctx context.Context, qtx *database.Queries, squadID uuid.UUID, steamIDs []string) error {
+	for i, steamID := range steamIDs {
+		err := qtx.AddSquadMember(ctx, database.AddSquadMemberParams{
+			SquadID:  squadID,
+			SteamID:  steamID,
+			Position: int32(i),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func databaseSquadToSquad(squad database.Squad, steamIDs []string) Squad {
+	if steamIDs == nil {
+		steamIDs = []string{}
+	}
+
+	return Squad{
+		ID:        squad.ID,
+		CreatedAt: squad.CreatedAt,
+		UpdatedAt: squad.UpdatedAt,
+		Name:      squad.Name,
+		SteamIDs:  steamIDs,
+	}
+}
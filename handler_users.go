@@ -1,24 +1,32 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Khazz0r/steam-lens/internal/api"
 	"github.com/Khazz0r/steam-lens/internal/auth"
 	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/Khazz0r/steam-lens/internal/validate"
 	"github.com/google/uuid"
 )
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Username  string    `json:"username"`
-	SteamID   string    `json:"steam_id"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Username      string    `json:"username"`
+	SteamID       string    `json:"steam_id"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified,omitempty"`
+	PendingEmail  string    `json:"pending_email,omitempty"`
 }
 
 func (cfg *config) handlerUserCreate(w http.ResponseWriter, req *http.Request) {
@@ -31,15 +39,16 @@ func (cfg *config) handlerUserCreate(w http.ResponseWriter, req *http.Request) {
 		User `json:"user"`
 	}
 
-	decoder := json.NewDecoder(req.Body)
 	params := parameters{}
-	err := decoder.Decode(&params)
-	if err != nil {
-		api.RespondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Username("username", params.Username)
+		v.Password("password", params.Password)
+		v.SteamID64("steam_id", params.SteamID)
+	}) {
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(params.Password)
+	hashedPassword, err := cfg.passwords.Hash(params.Password)
 	if err != nil {
 		api.RespondWithError(w, http.StatusInternalServerError, "Error hashing password", err)
 		return
@@ -54,7 +63,7 @@ func (cfg *config) handlerUserCreate(w http.ResponseWriter, req *http.Request) {
 		SteamID:        params.SteamID,
 	})
 	if err != nil {
-		api.RespondWithError(w, http.StatusInternalServerError, "User already exists", err)
+		api.RespondWithAppError(w, api.DatabaseError(err, "Unable to create user"))
 		return
 	}
 
@@ -80,33 +89,119 @@ func (cfg *config) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		Username string `json:"username"`
 		Password string `json:"password"`
 	}
-	type response struct {
-		User         `json:"user"`
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
-	}
 
-	decoder := json.NewDecoder(req.Body)
 	params := parameters{}
-	err := decoder.Decode(&params)
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		v.Required("username", params.Username)
+		v.Required("password", params.Password)
+	}) {
+		return
+	}
+
+	keys := loginKeys(params.Username, clientIP(req))
+
+	lockedUntil, err := cfg.loginLockedUntil(req.Context(), keys)
 	if err != nil {
-		api.RespondWithError(w, http.StatusInternalServerError, "Error decoding request", err)
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to check login attempts", err)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		cfg.recordAuditEvent(req, uuid.Nil, auditLoginLocked, map[string]any{"username": params.Username})
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(lockedUntil).Seconds())+1))
+		api.RespondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, please try again later", nil)
 		return
 	}
 
+	// Unknown usernames and wrong passwords get the same response and the same hashing cost
+	// so the login endpoint can't be used to find out which usernames exist
+	needsRehash := false
 	user, err := cfg.db.GetUserByUsername(req.Context(), params.Username)
 	if err != nil {
-		api.RespondWithError(w, http.StatusBadRequest, "The username provided does not exist", err)
+		cfg.passwords.CheckDummy(params.Password)
+	} else {
+		needsRehash, err = cfg.passwords.Check(user.HashedPassword, params.Password)
+	}
+	if err != nil {
+		cfg.recordAuditEvent(req, user.ID, auditLoginFailed, map[string]any{"username": params.Username, "reason": "invalid_credentials"})
+
+		delay, recordErr := cfg.recordFailedLogin(req.Context(), keys)
+		if recordErr != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "Unable to record failed login attempt", recordErr)
+			return
+		}
+		sleepWithContext(req.Context(), delay)
+
+		api.RespondWithAppError(w, api.NewError(http.StatusUnauthorized, api.CodeInvalidCredentials, "Invalid username or password", err))
 		return
 	}
 
-	err = auth.CheckPasswordHash(user.HashedPassword, params.Password)
+	if needsRehash {
+		cfg.rehashPassword(req.Context(), user, params.Password)
+	}
+
+	// Accounts with two-factor enabled only get a short-lived pending token here, the session
+	// is handed out by handlerLoginMFA once a valid code is provided. Failed attempts are only cleared
+	// there, otherwise logging in again with the password would reset the throttle between code guesses
+	if user.TotpEnabled {
+		mfaToken, err := cfg.jwtKeys.MakeMFAPendingToken(user.ID, mfaPendingTokenExpiry)
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "Could not make two-factor token", err)
+			return
+		}
+
+		api.RespondWithJSON(w, http.StatusOK, mfaPendingResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
+		return
+	}
+
+	err = cfg.clearFailedLogins(req.Context(), user.Username)
 	if err != nil {
-		api.RespondWithError(w, http.StatusUnauthorized, "Cannot login, wrong password provided", err)
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to clear failed login attempts", err)
 		return
 	}
 
-	accessToken, err := auth.MakeJWTToken(user.ID, cfg.jwtSecret, time.Hour)
+	cfg.respondWithSession(w, req, user)
+}
+
+// Upgrades a hash made with an old algorithm or weaker settings now that the plain password is known.
+// It only replaces the exact hash that was checked so a password changed in the meantime isn't overwritten,
+// and a failure is only logged since the login itself already succeeded
+func (cfg *config) rehashPassword(ctx context.Context, user database.User, password string) {
+	newHash, err := cfg.passwords.Hash(password)
+	if err != nil {
+		log.Printf("Unable to rehash password for user %s: %v", user.ID, err)
+		return
+	}
+
+	_, err = cfg.db.RehashUserPassword(ctx, database.RehashUserPasswordParams{
+		NewHash: newHash,
+		ID:      user.ID,
+		OldHash: user.HashedPassword,
+	})
+	if err != nil {
+		log.Printf("Unable to store rehashed password for user %s: %v", user.ID, err)
+	}
+}
+
+// Creates the access and refresh tokens for a user who has fully logged in, sets them as cookies and responds with the user
+func (cfg *config) respondWithSession(w http.ResponseWriter, req *http.Request, user database.User) {
+	type response struct {
+		User         `json:"user"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		CSRFToken    string `json:"csrf_token"`
+	}
+
+	if user.DisabledAt.Valid {
+		api.RespondWithError(w, http.StatusForbidden, "This account has been disabled", nil)
+		return
+	}
+
+	cfg.recordAuditEvent(req, user.ID, auditLoginSucceeded, map[string]any{"mfa": user.TotpEnabled})
+
+	accessToken, err := cfg.jwtKeys.MakeJWTToken(user.ID, time.Hour)
 	if err != nil {
 		api.RespondWithError(w, http.StatusInternalServerError, "Could not make JWT token", err)
 		return
@@ -130,19 +225,19 @@ func (cfg *config) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// A new CSRF token on every login so one planted before the user logged in is useless
+	csrfToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Could not make CSRF token", err)
+		return
+	}
+
 	// get platform to determine if dev or prod, if dev make devPlatform false for secure cookies
 	devPlatform := os.Getenv("PLATFORM") != "dev"
 
 	// Set HttpOnly cookies for both tokens
-	http.SetCookie(w, &http.Cookie{
-		Name:     "JWT_token",
-		Value:    accessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   devPlatform,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(time.Hour),
-	})
+	setAccessTokenCookie(w, accessToken)
+	setCSRFCookie(w, csrfToken)
 	http.SetCookie(w, &http.Cookie{
 		Name:     "refresh_token",
 		Value:    refreshToken,
@@ -163,13 +258,11 @@ func (cfg *config) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		},
 		Token:        accessToken,  // remove this when ran in a prod environment
 		RefreshToken: refreshToken, // remove too
+		CSRFToken:    csrfToken,
 	})
 }
 
 func (cfg *config) handlerLogout(w http.ResponseWriter, req *http.Request) {
-	// get platform to determine if dev or prod, if dev make devPlatform false for secure cookies
-	devPlatform := os.Getenv("PLATFORM") != "dev"
-
 	refreshCookie, err := req.Cookie("refresh_token")
 	if err != nil {
 		api.RespondWithError(w, http.StatusBadRequest, "Unable to retrieve refresh_token from request", err)
@@ -186,7 +279,75 @@ func (cfg *config) handlerLogout(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Invalidate both JWT token and refresh token cookies for logging out
+	userID, _ := req.Context().Value(userIDContextKey).(uuid.UUID)
+	cfg.recordAuditEvent(req, userID, auditLogout, nil)
+	cfg.recordAuditEvent(req, userID, auditTokenRevoked, map[string]any{"scope": "current_session"})
+
+	clearSessionCookies(w)
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"message": "Successfully logged out"}`))
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to write out logout message", err)
+		return
+	}
+}
+
+func setAccessTokenCookie(w http.ResponseWriter, accessToken string) {
+	// get platform to determine if dev or prod, if dev make devPlatform false for secure cookies
+	devPlatform := os.Getenv("PLATFORM") != "dev"
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "JWT_token",
+		Value:    accessToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   devPlatform,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(time.Hour),
+	})
+}
+
+// Ends every session of the user except the one making the request. Other refresh tokens are revoked,
+// every access token issued so far is invalidated and the caller gets a fresh access token cookie
+func (cfg *config) endOtherSessions(w http.ResponseWriter, req *http.Request, userID uuid.UUID) error {
+	currentRefreshToken := ""
+	if refreshCookie, err := req.Cookie("refresh_token"); err == nil {
+		currentRefreshToken = refreshCookie.Value
+	}
+
+	err := cfg.db.RevokeOtherUserRefreshTokens(req.Context(), database.RevokeOtherUserRefreshTokensParams{
+		UserID: userID,
+		Token:  currentRefreshToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	revokedAt := time.Now().UTC()
+	_, err = cfg.db.RevokeUserSessions(req.Context(), database.RevokeUserSessionsParams{
+		ID:                userID,
+		SessionsRevokedAt: sql.NullTime{Time: revokedAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tokens issued in the second of the revocation are rejected, so the caller's new token is dated to the next one
+	accessToken, err := cfg.jwtKeys.MakeJWTTokenIssuedAt(userID, revokedAt.Truncate(time.Second).Add(time.Second), time.Hour)
+	if err != nil {
+		return err
+	}
+	setAccessTokenCookie(w, accessToken)
+
+	return nil
+}
+
+// Invalidate the JWT token, refresh token and CSRF token cookies, used when logging out or when the account goes away
+func clearSessionCookies(w http.ResponseWriter) {
+	// get platform to determine if dev or prod, if dev make devPlatform false for secure cookies
+	devPlatform := os.Getenv("PLATFORM") != "dev"
+
 	http.SetCookie(w, &http.Cookie{
 		Name:     "JWT_token",
 		Value:    "",
@@ -205,13 +366,14 @@ func (cfg *config) handlerLogout(w http.ResponseWriter, req *http.Request) {
 		SameSite: http.SameSiteLaxMode,
 		Expires:  time.Now().Add(-1 * time.Hour),
 	})
-
-	w.WriteHeader(http.StatusOK)
-	_, err = w.Write([]byte(`{"message": "Successfully logged out"}`))
-	if err != nil {
-		api.RespondWithError(w, http.StatusInternalServerError, "Unable to write out logout message", err)
-		return
-	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    "",
+		Path:     "/",
+		Secure:   devPlatform,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(-1 * time.Hour),
+	})
 }
 
 func (cfg *config) handlerGetMe(w http.ResponseWriter, req *http.Request) {
@@ -231,19 +393,24 @@ func (cfg *config) handlerGetMe(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", userETag(user))
 	api.RespondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:       user.ID,
-			Username: user.Username,
-			SteamID:  user.SteamID,
-		},
+		User: databaseUserToUser(user),
 	})
 }
 
 type updateUserParams struct {
-	Username *string `json:"username"`
-	Password *string `json:"password"`
-	SteamID  *string `json:"steam_id"`
+	Username        *string `json:"username"`
+	Password        *string `json:"password"`
+	SteamID         *string `json:"steam_id"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// Reports whether the request would change any field, empty values are ignored by the update
+func (params updateUserParams) changesAnything() bool {
+	return (params.Username != nil && strings.TrimSpace(*params.Username) != "") ||
+		(params.Password != nil && strings.TrimSpace(*params.Password) != "") ||
+		(params.SteamID != nil && strings.TrimSpace(*params.SteamID) != "")
 }
 
 // Helper function to safely dereference a string pointer that could be empty
@@ -265,19 +432,39 @@ func (cfg *config) handlerUpdateUser(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	user, err := cfg.db.GetUserByID(req.Context(), userID)
-	if err != nil {
-		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", err)
+	// Fields left out or sent empty aren't changed, anything else has to pass the same rules as sign up
+	params := updateUserParams{}
+	if !decodeAndValidate(w, req, &params, func(v *validate.Validator) {
+		if params.Username != nil && strings.TrimSpace(*params.Username) != "" {
+			v.Username("username", *params.Username)
+		}
+		if params.Password != nil && strings.TrimSpace(*params.Password) != "" {
+			v.Password("password", *params.Password)
+		}
+		if params.SteamID != nil && strings.TrimSpace(*params.SteamID) != "" {
+			v.SteamID64("steam_id", *params.SteamID)
+		}
+		// Every field here can be used to take over the account, so a stolen cookie alone isn't enough
+		if params.changesAnything() {
+			v.Required("current_password", params.CurrentPassword)
+		}
+	}) {
 		return
 	}
 
-	decoder := json.NewDecoder(req.Body)
+	if params.changesAnything() {
+		currentUser, err := cfg.db.GetUserByID(req.Context(), userID)
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", err)
+			return
+		}
 
-	params := updateUserParams{}
-	err = decoder.Decode(&params)
-	if err != nil {
-		api.RespondWithError(w, http.StatusInternalServerError, "Unable to decode request body", err)
-		return
+		_, err = cfg.passwords.Check(currentUser.HashedPassword, params.CurrentPassword)
+		if err != nil {
+			cfg.recordAuditEvent(req, userID, auditReauthFailed, nil)
+			api.RespondWithAppError(w, api.NewError(http.StatusUnauthorized, api.CodeInvalidCredentials, "Current password is incorrect", err))
+			return
+		}
 	}
 
 	var usernamePtr *string
@@ -288,8 +475,9 @@ func (cfg *config) handlerUpdateUser(w http.ResponseWriter, req *http.Request) {
 		usernamePtr = params.Username
 	}
 
+	// Hashing happens before the transaction starts so the row isn't locked while bcrypt runs
 	if params.Password != nil && strings.TrimSpace(*params.Password) != "" {
-		hash, err := auth.HashPassword(*params.Password)
+		hash, err := cfg.passwords.Hash(*params.Password)
 		if err != nil {
 			api.RespondWithError(w, http.StatusBadRequest, "Unable to hash password", err)
 			return
@@ -301,7 +489,33 @@ func (cfg *config) handlerUpdateUser(w http.ResponseWriter, req *http.Request) {
 		steamIDPtr = params.SteamID
 	}
 
-	err = cfg.db.UpdateUser(req.Context(), database.UpdateUserParams{
+	tx, err := cfg.sqlDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "Unable to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// The row is locked so the If-Match check and the update can't be split by another write
+	user, err := qtx.GetUserByIDForUpdate(req.Context(), userID)
+	if err != nil {
+		api.RespondWithError(w, http.StatusNotFound, "Could not find user by that ID", err)
+		return
+	}
+
+	if !ifMatchSatisfied(req.Header.Get("If-Match"), userETag(user)) {
+		w.Header().Set("ETag", userETag(user))
+		api.RespondWithAppError(w, &api.Error{
+			Status:  http.StatusPreconditionFailed,
+			Code:    api.CodePreconditionFailed,
+			Message: "The account was changed since it was loaded, reload it and try again",
+			Details: map[string]any{"updated_at": user.UpdatedAt},
+		})
+		return
+	}
+
+	updatedUser, err := qtx.UpdateUser(req.Context(), database.UpdateUserParams{
 		ID:        userID,
 		Column1:   deref(usernamePtr),
 		Column2:   deref(hashedPasswordPtr),
@@ -309,14 +523,69 @@ func (cfg *config) handlerUpdateUser(w http.ResponseWriter, req *http.Request) {
 		UpdatedAt: time.Now().UTC(),
 	})
 	if err != nil {
-		api.RespondWithError(w, http.StatusInternalServerError, "Failed to update user with provided fields, duplicate username found", err)
+		api.RespondWithAppError(w, api.DatabaseError(err, "Failed to update user with provided fields"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		api.RespondWithAppError(w, api.DatabaseError(err, "Failed to update user with provided fields"))
 		return
 	}
 
+	if usernamePtr != nil && *usernamePtr != user.Username {
+		cfg.recordAuditEvent(req, userID, auditUsernameChanged, map[string]any{"old": user.Username, "new": *usernamePtr})
+	}
+	if hashedPasswordPtr != nil {
+		cfg.recordAuditEvent(req, userID, auditPasswordChanged, nil)
+
+		// Anyone else who was logged in with the old password gets logged out
+		err = cfg.endOtherSessions(w, req, userID)
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "Password changed but unable to end other sessions", err)
+			return
+		}
+		cfg.recordAuditEvent(req, userID, auditTokenRevoked, map[string]any{"scope": "other_sessions"})
+	}
+	if steamIDPtr != nil && *steamIDPtr != user.SteamID {
+		cfg.recordAuditEvent(req, userID, auditSteamIDChanged, map[string]any{"old": user.SteamID, "new": *steamIDPtr})
+	}
+
+	w.Header().Set("ETag", userETag(updatedUser))
 	api.RespondWithJSON(w, http.StatusOK, response{
-		User: User{
-			Username: user.Username,
-			SteamID:  user.SteamID,
-		},
+		User: databaseUserToUser(updatedUser),
 	})
 }
+
+func databaseUserToUser(user database.User) User {
+	return User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Username:      user.Username,
+		SteamID:       user.SteamID,
+		Email:         user.Email.String,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		PendingEmail:  user.PendingEmail.String,
+	}
+}
+
+// The ETag of a user is its updated_at, so any change made from somewhere else makes an old one stale
+func userETag(user database.User) string {
+	return fmt.Sprintf(`"%d"`, user.UpdatedAt.UnixMicro())
+}
+
+// Helper function to check an If-Match header against the current ETag, no header means no precondition
+func ifMatchSatisfied(header, etag string) bool {
+	if strings.TrimSpace(header) == "" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
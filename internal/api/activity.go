@@ -0,0 +1,112 @@
+package api
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"time"
+)
+
+type FriendPlaytime struct {
+	SteamID         string `json:"steamID"`
+	Playtime2Weeks  int    `json:"playtime_2weeks"`
+	PlaytimeForever int    `json:"playtime_forever"`
+}
+
+type GameActivity struct {
+	AppID               int              `json:"appID"`
+	Name                string           `json:"name"`
+	ImgIconURL          string           `json:"img_icon_url"`
+	TotalPlaytime2Weeks int              `json:"totalPlaytime2Weeks"`
+	Friends             []FriendPlaytime `json:"friends"`
+}
+
+type ActivityFeed struct {
+	FriendCount       int            `json:"friendCount"`
+	ActiveFriendCount int            `json:"activeFriendCount"`
+	Games             []GameActivity `json:"games"`
+}
+
+// Gets the recently played games of every friend, paced the same way as the matched games ranking.
+// Friends whose games can't be read (private profiles mostly) just don't show up in the feed
+func (apicfg *ApiConfig) FriendActivity(friends []Friend) ActivityFeed {
+	if len(friends) == 0 {
+		return buildActivityFeed(nil, 0)
+	}
+
+	ticker := time.NewTicker(friendRequestInterval(len(friends)))
+	defer ticker.Stop()
+
+	libraries := []RecentlyPlayedGames{}
+	for _, friend := range friends {
+		// Cached friends don't need to wait for the ticker since they don't call Steam
+		recentGames, found := apicfg.RecentlyPlayedCache.ReadCache(friend.SteamID)
+		if !found {
+			<-ticker.C
+			var err error
+			recentGames, err = apicfg.FetchRecentlyPlayedGames(friend.SteamID)
+			if err != nil {
+				log.Printf("Error getting recently played games for %s: %v", friend.SteamID, err)
+				continue
+			}
+		}
+		libraries = append(libraries, recentGames)
+	}
+
+	return buildActivityFeed(libraries, len(friends))
+}
+
+// Helper function that groups recently played games by game, games are sorted by the combined hours friends
+// played them in the last two weeks and each game's friends by who played it the most
+func buildActivityFeed(libraries []RecentlyPlayedGames, friendCount int) ActivityFeed {
+	feed := ActivityFeed{
+		FriendCount: friendCount,
+		Games:       []GameActivity{},
+	}
+
+	byApp := map[int]*GameActivity{}
+	for _, library := range libraries {
+		active := false
+		for _, game := range library.Games {
+			if game.Playtime2Weeks <= 0 {
+				continue
+			}
+			active = true
+
+			activity, found := byApp[game.AppID]
+			if !found {
+				activity = &GameActivity{
+					AppID:      game.AppID,
+					Name:       game.Name,
+					ImgIconURL: game.ImgIconURL,
+				}
+				byApp[game.AppID] = activity
+			}
+			activity.TotalPlaytime2Weeks += game.Playtime2Weeks
+			activity.Friends = append(activity.Friends, FriendPlaytime{
+				SteamID:         library.SteamID,
+				Playtime2Weeks:  game.Playtime2Weeks,
+				PlaytimeForever: game.PlaytimeForever,
+			})
+		}
+		if active {
+			feed.ActiveFriendCount++
+		}
+	}
+
+	for _, activity := range byApp {
+		slices.SortFunc(activity.Friends, func(a, b FriendPlaytime) int {
+			return cmp.Compare(b.Playtime2Weeks, a.Playtime2Weeks)
+		})
+		feed.Games = append(feed.Games, *activity)
+	}
+
+	slices.SortFunc(feed.Games, func(a, b GameActivity) int {
+		if a.TotalPlaytime2Weeks != b.TotalPlaytime2Weeks {
+			return cmp.Compare(b.TotalPlaytime2Weeks, a.TotalPlaytime2Weeks)
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return feed
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestBuildActivityFeed(t *testing.T) {
+	libraries := []RecentlyPlayedGames{
+		{
+			SteamID: "1",
+			Games: []Game{
+				{AppID: 548430, Name: "Deep Rock Galactic", Playtime2Weeks: 300, PlaytimeForever: 5000},
+				{AppID: 620, Name: "Portal 2", Playtime2Weeks: 60, PlaytimeForever: 900},
+			},
+		},
+		{
+			SteamID: "2",
+			Games: []Game{
+				{AppID: 548430, Name: "Deep Rock Galactic", Playtime2Weeks: 600, PlaytimeForever: 800},
+			},
+		},
+		// A private profile comes back without games
+		{SteamID: "3"},
+	}
+
+	feed := buildActivityFeed(libraries, 4)
+
+	if feed.FriendCount != 4 || feed.ActiveFriendCount != 2 {
+		t.Errorf("expected 4 friends with 2 active, got %d and %d", feed.FriendCount, feed.ActiveFriendCount)
+	}
+	if len(feed.Games) != 2 {
+		t.Fatalf("expected 2 games, got %v", feed.Games)
+	}
+
+	top := feed.Games[0]
+	if top.AppID != 548430 || top.TotalPlaytime2Weeks != 900 {
+		t.Errorf("expected Deep Rock Galactic with 900 minutes first, got %+v", top)
+	}
+	if len(top.Friends) != 2 || top.Friends[0].SteamID != "2" {
+		t.Errorf("expected Steam ID 2 to be listed first for Deep Rock Galactic, got %v", top.Friends)
+	}
+	if feed.Games[1].AppID != 620 {
+		t.Errorf("expected Portal 2 second, got %+v", feed.Games[1])
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"slices"
 	"strings"
+	"sync"
 )
 
 const steamMainAPIURL = "http://api.steampowered.com/"
@@ -18,17 +19,37 @@ const steamUserURL = "ISteamUser/"
 const steamPlayerURL = "IPlayerService/"
 const steamAchievementURL = "ISteamUserStats/"
 
+// GetPlayerSummaries fetches this many batches of Steam IDs at once
+const playerSummariesConcurrency = 4
+
+// Steam leaves out private fields (timeCreated, locCountryCode) for private profiles, and gameID/gameExtraInfo
+// when the player isn't in a game. PersonaState is 0 offline, 1 online, 2 busy, 3 away, 4 snooze,
+// 5 looking to trade and 6 looking to play
 type Player struct {
 	SteamID                  string `json:"steamID"`
 	CommunityVisibilityState int    `json:"communityVisibilityState"`
 	PersonaName              string `json:"personaName"`
+	ProfileURL               string `json:"profileURL"`
 	Avatar                   string `json:"avatar"`
 	AvatarMedium             string `json:"avatarMedium"`
 	AvatarFull               string `json:"avatarFull"`
+	PersonaState             int    `json:"personaState"`
+	LastLogoff               int64  `json:"lastLogoff,omitempty"`
+	GameID                   string `json:"gameID,omitempty"`
+	GameExtraInfo            string `json:"gameExtraInfo,omitempty"`
+	TimeCreated              int64  `json:"timeCreated,omitempty"`
+	LocCountryCode           string `json:"locCountryCode,omitempty"`
+
+	// Only filled in when asked for with the include query parameter
+	Bans       *PlayerBans `json:"bans,omitempty"`
+	SteamLevel *int        `json:"steamLevel,omitempty"`
+	Badges     *Badges     `json:"badges,omitempty"`
 }
 
+// Missing lists requested Steam IDs that Steam didn't return a summary for, usually because they don't exist
 type Summaries struct {
 	Players []Player `json:"players"`
+	Missing []string `json:"missing,omitempty"`
 }
 
 type SummariesResponse struct {
@@ -39,8 +60,14 @@ type SummariesResponse struct {
 func (apicfg *ApiConfig) GetPlayerSummaries(steamIDs []string) (Summaries, error) {
 	uncachedIDs := []string{}
 	cachedPlayers := []Player{}
+	seen := map[string]bool{}
 
 	for _, steamID := range steamIDs {
+		if seen[steamID] {
+			continue
+		}
+		seen[steamID] = true
+
 		cache, found := apicfg.PlayerCache.ReadCache(steamID)
 		if found {
 			log.Printf("Cache found for steamID: %s\n", steamID)
@@ -51,19 +78,95 @@ func (apicfg *ApiConfig) GetPlayerSummaries(steamIDs []string) (Summaries, error
 	}
 
 	if len(uncachedIDs) == 0 {
-		slices.SortFunc(cachedPlayers, func(i Player, j Player) int {
-			return cmp.Compare(i.SteamID, j.SteamID)
-		})
-		return Summaries{
-			Players: cachedPlayers,
-		}, nil
+		return mergePlayerSummaries(nil, cachedPlayers, nil), nil
+	}
+
+	players, err := apicfg.fetchPlayerSummaryBatches(uncachedIDs)
+	if err != nil {
+		return Summaries{}, err
+	}
+
+	for _, player := range players {
+		log.Printf("Adding player to cache with steamID: %s\n", player.SteamID)
+		apicfg.PlayerCache.UpdateCache(player.SteamID, player)
+	}
+
+	return mergePlayerSummaries(uncachedIDs, cachedPlayers, players), nil
+}
+
+// Helper function that combines cached and fetched players sorted by Steam ID, requested IDs that weren't
+// fetched are reported as missing in the order they were asked for
+func mergePlayerSummaries(requestedIDs []string, cachedPlayers, fetchedPlayers []Player) Summaries {
+	fetched := map[string]bool{}
+	for _, player := range fetchedPlayers {
+		fetched[player.SteamID] = true
+	}
+
+	missing := []string{}
+	for _, steamID := range requestedIDs {
+		if !fetched[steamID] {
+			missing = append(missing, steamID)
+		}
+	}
+	if len(missing) == 0 {
+		missing = nil
+	}
+
+	players := append(slices.Clone(cachedPlayers), fetchedPlayers...)
+	slices.SortFunc(players, func(i Player, j Player) int {
+		return cmp.Compare(i.SteamID, j.SteamID)
+	})
+
+	return Summaries{
+		Players: players,
+		Missing: missing,
+	}
+}
+
+// Splits the Steam IDs into batches Steam accepts and fetches a few batches at once. If any batch fails
+// the whole call fails and nothing gets cached
+func (apicfg *ApiConfig) fetchPlayerSummaryBatches(steamIDs []string) ([]Player, error) {
+	batches := [][]string{}
+	for start := 0; start < len(steamIDs); start += steamIDBatchSize {
+		batches = append(batches, steamIDs[start:min(start+steamIDBatchSize, len(steamIDs))])
+	}
+
+	results := make([][]Player, len(batches))
+	errs := make([]error, len(batches))
+
+	semaphore := make(chan struct{}, playerSummariesConcurrency)
+	waitGroup := sync.WaitGroup{}
+	for i, batch := range batches {
+		waitGroup.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, batch []string) {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+
+			results[i], errs[i] = apicfg.fetchPlayerSummaries(batch)
+		}(i, batch)
+	}
+	waitGroup.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	players := []Player{}
+	for _, result := range results {
+		players = append(players, result...)
 	}
 
-	joinedIDs := strings.Join(uncachedIDs, ",")
+	return players, nil
+}
+
+// Make API call to Steam's GetPlayerSummaries endpoint without looking at any cache, Steam takes at most 100 IDs
+func (apicfg *ApiConfig) fetchPlayerSummaries(steamIDs []string) ([]Player, error) {
+	joinedIDs := strings.Join(steamIDs, ",")
 
 	baseURL, err := url.Parse(steamMainAPIURL)
 	if err != nil {
-		return Summaries{}, err
+		return nil, err
 	}
 
 	fullURL := baseURL.JoinPath(steamUserURL, "GetPlayerSummaries", "v0002/")
@@ -76,7 +179,7 @@ func (apicfg *ApiConfig) GetPlayerSummaries(steamIDs []string) (Summaries, error
 
 	resp, err := http.Get(fullURL.String())
 	if err != nil {
-		return Summaries{}, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -84,7 +187,7 @@ func (apicfg *ApiConfig) GetPlayerSummaries(steamIDs []string) (Summaries, error
 	if !strings.HasPrefix(contentType, "application/json") {
 		testBody, _ := io.ReadAll(resp.Body)
 		fmt.Printf("Unexpected response from Steam API: %s\n", testBody)
-		return Summaries{}, errors.New("steam API returned non-JSON response")
+		return nil, errors.New("steam API returned non-JSON response")
 	}
 
 	decoder := json.NewDecoder(resp.Body)
@@ -92,23 +195,20 @@ func (apicfg *ApiConfig) GetPlayerSummaries(steamIDs []string) (Summaries, error
 	body := SummariesResponse{}
 	err = decoder.Decode(&body)
 	if err != nil {
-		return Summaries{}, err
-	}
-
-	allPlayers := body.Response.Players
-	for _, player := range allPlayers {
-		log.Printf("Adding player to cache with steamID: %s\n", player.SteamID)
-		apicfg.PlayerCache.UpdateCache(player.SteamID, player)
+		return nil, err
 	}
 
-	return body.Response, nil
+	return body.Response.Players, nil
 }
 
-// For now, imgIconURL returns img_icon_url for json for since Steam's API uses snake case
+// For now, imgIconURL returns img_icon_url for json for since Steam's API uses snake case, same goes for playtime
+// which Steam reports in minutes. Playtime2Weeks is left out by Steam for games not played in the last two weeks
 type Game struct {
-	AppID      int    `json:"appID"`
-	Name       string `json:"name"`
-	ImgIconURL string `json:"img_icon_url"`
+	AppID           int    `json:"appID"`
+	Name            string `json:"name"`
+	ImgIconURL      string `json:"img_icon_url"`
+	PlaytimeForever int    `json:"playtime_forever"`
+	Playtime2Weeks  int    `json:"playtime_2weeks"`
 }
 
 type OwnedGames struct {
@@ -123,12 +223,18 @@ type OwnedGamesResponse struct {
 
 // Make API call to Steam's GetOwnedGames endpoint to obtain all owned games for a user
 func (apicfg *ApiConfig) GetOwnedGames(steamID string) (OwnedGames, error) {
-	_, found := apicfg.OwnedGamesCache.ReadCache(steamID)
+	ownedGames, found := apicfg.OwnedGamesCache.ReadCache(steamID)
 	if found {
 		log.Printf("OwnedGames cache found for %s\n", steamID)
-		return apicfg.OwnedGamesCache.Cache[steamID].Data, nil
+		return ownedGames, nil
 	}
 
+	return apicfg.FetchOwnedGames(steamID)
+}
+
+// Same as GetOwnedGames but always asks Steam, for when stale data isn't good enough (like snapshots).
+// The fresh result still goes into the cache
+func (apicfg *ApiConfig) FetchOwnedGames(steamID string) (OwnedGames, error) {
 	baseURL, err := url.Parse(steamMainAPIURL)
 	if err != nil {
 		return OwnedGames{}, err
@@ -171,6 +277,71 @@ func (apicfg *ApiConfig) GetOwnedGames(steamID string) (OwnedGames, error) {
 	return body.Response, nil
 }
 
+type RecentlyPlayedGames struct {
+	SteamID    string
+	TotalCount int    `json:"total_count"`
+	Games      []Game `json:"games"`
+}
+
+type RecentlyPlayedGamesResponse struct {
+	Response RecentlyPlayedGames `json:"response"`
+}
+
+// Make API call to Steam's GetRecentlyPlayedGames endpoint to obtain the games a user played in the last two weeks,
+// this includes games they don't own like free weekends and family shared games
+func (apicfg *ApiConfig) GetRecentlyPlayedGames(steamID string) (RecentlyPlayedGames, error) {
+	cache, found := apicfg.RecentlyPlayedCache.ReadCache(steamID)
+	if found {
+		log.Printf("RecentlyPlayed cache found for %s\n", steamID)
+		return cache, nil
+	}
+
+	return apicfg.FetchRecentlyPlayedGames(steamID)
+}
+
+// Same as GetRecentlyPlayedGames but always asks Steam, the fresh result still goes into the cache
+func (apicfg *ApiConfig) FetchRecentlyPlayedGames(steamID string) (RecentlyPlayedGames, error) {
+	baseURL, err := url.Parse(steamMainAPIURL)
+	if err != nil {
+		return RecentlyPlayedGames{}, err
+	}
+
+	fullURL := baseURL.JoinPath(steamPlayerURL, "GetRecentlyPlayedGames", "v0001/")
+
+	query := url.Values{}
+	query.Set("key", apicfg.SteamApiKey)
+	query.Set("steamid", steamID)
+
+	fullURL.RawQuery = query.Encode()
+
+	resp, err := http.Get(fullURL.String())
+	if err != nil {
+		return RecentlyPlayedGames{}, err
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/json") {
+		testBody, _ := io.ReadAll(resp.Body)
+		fmt.Printf("Unexpected response from Steam API: %s\n", testBody)
+		return RecentlyPlayedGames{}, errors.New("steam API returned non-JSON response")
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+
+	body := RecentlyPlayedGamesResponse{}
+	err = decoder.Decode(&body)
+	if err != nil {
+		return RecentlyPlayedGames{}, err
+	}
+
+	body.Response.SteamID = steamID
+
+	apicfg.RecentlyPlayedCache.UpdateCache(steamID, body.Response)
+
+	return body.Response, nil
+}
+
 type Friend struct {
 	SteamID      string `json:"steamID"`
 	Relationship string `json:"relationship"`
@@ -187,10 +358,10 @@ type FriendListResponse struct {
 
 // Make API call to Steam's GetFriendList endpoint to obtain all friends for a user
 func (apicfg *ApiConfig) GetFriendList(steamID string) (FriendList, error) {
-	_, found := apicfg.FriendListCache.ReadCache(steamID)
+	friendList, found := apicfg.FriendListCache.ReadCache(steamID)
 	if found {
 		log.Printf("FriendList cache found for %s\n", steamID)
-		return apicfg.FriendListCache.Cache[steamID].Data, nil
+		return friendList, nil
 	}
 
 	baseURL, err := url.Parse(steamMainAPIURL)
@@ -259,10 +430,10 @@ type ConvertedPlayerAchievements struct {
 // Make API call to Steam's GetPlayerAchievements endpoint to obtain all achievements for a game
 func (apicfg *ApiConfig) GetPlayerAchievements(steamID, appID string) (ConvertedPlayerAchievements, error) {
 	cacheKey := steamID + "-" + appID
-	_, found := apicfg.AchievementsCache.ReadCache(cacheKey)
+	achievements, found := apicfg.AchievementsCache.ReadCache(cacheKey)
 	if found {
 		log.Printf("Achievements cache found for %s\n", cacheKey)
-		return apicfg.AchievementsCache.Cache[cacheKey].Data, nil
+		return achievements, nil
 	}
 
 	baseURL, err := url.Parse(steamMainAPIURL)
@@ -331,6 +502,9 @@ type ComparedMatchedGames struct {
 	Matches          int     `json:"matches"`
 	MatchingGames    []Game  `json:"matchingGames"`
 	FriendOnlyGames  []Game  `json:"friendOnlyGames"`
+	// Only filled in when ranking by taste
+	TasteSimilarity float64       `json:"tasteSimilarity,omitempty"`
+	SharedGenres    []SharedGenre `json:"sharedGenres,omitempty"`
 }
 
 // Run comparisons on user and their friend's games to get overall ranking
@@ -343,8 +517,14 @@ func (userGames OwnedGames) CompareOwnedGames(friendGames OwnedGames, listGames
 	result.MatchingGames = []Game{}
 	result.FriendOnlyGames = []Game{}
 
+	// Games are matched by app ID only, playtime is different for everyone
+	userAppIDs := map[int]bool{}
+	for _, game := range userGames.Games {
+		userAppIDs[game.AppID] = true
+	}
+
 	for _, game := range friendGames.Games {
-		if slices.Contains(userGames.Games, game) {
+		if userAppIDs[game.AppID] {
 			result.MatchingGames = append(result.MatchingGames, game)
 		} else {
 			result.FriendOnlyGames = append(result.FriendOnlyGames, game)
@@ -361,9 +541,7 @@ func (userGames OwnedGames) CompareOwnedGames(friendGames OwnedGames, listGames
 		result.FriendPercentage = float64(result.Matches) / float64(friendGames.GameCount)
 	}
 
-	matchesWeight := 0.6
-	percentWeight := 0.4
-	result.Score = float64(result.Matches)*matchesWeight + result.FriendPercentage*100.0*percentWeight
+	result.Score = comparisonScore(result.Matches, result.FriendPercentage)
 
 	if !listGames {
 		result.MatchingGames = nil
@@ -372,3 +550,9 @@ func (userGames OwnedGames) CompareOwnedGames(friendGames OwnedGames, listGames
 
 	return result
 }
+
+func comparisonScore(matches int, friendPercentage float64) float64 {
+	matchesWeight := 0.6
+	percentWeight := 0.4
+	return float64(matches)*matchesWeight + friendPercentage*100.0*percentWeight
+}
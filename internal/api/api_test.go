@@ -0,0 +1,41 @@
+package api
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMergePlayerSummaries(t *testing.T) {
+	cached := []Player{{SteamID: "3"}, {SteamID: "1"}}
+	fetched := []Player{{SteamID: "4"}, {SteamID: "2"}}
+
+	summaries := mergePlayerSummaries([]string{"5", "2", "4", "6"}, cached, fetched)
+
+	steamIDs := []string{}
+	for _, player := range summaries.Players {
+		steamIDs = append(steamIDs, player.SteamID)
+	}
+	if !slices.Equal(steamIDs, []string{"1", "2", "3", "4"}) {
+		t.Errorf("expected cached and fetched players sorted by Steam ID, got %v", steamIDs)
+	}
+	if !slices.Equal(summaries.Missing, []string{"5", "6"}) {
+		t.Errorf("expected Steam IDs 5 and 6 to be missing, got %v", summaries.Missing)
+	}
+}
+
+func TestGetPlayerSummariesFromCache(t *testing.T) {
+	apicfg := &ApiConfig{
+		PlayerCache: Cache[Player]{Cache: map[string]CachedData[Player]{}, RenewTime: time.Hour},
+	}
+	apicfg.PlayerCache.UpdateCache("2", Player{SteamID: "2"})
+	apicfg.PlayerCache.UpdateCache("1", Player{SteamID: "1"})
+
+	summaries, err := apicfg.GetPlayerSummaries([]string{"2", "1", "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(summaries.Players) != 2 || summaries.Players[0].SteamID != "1" || summaries.Missing != nil {
+		t.Errorf("expected Steam IDs 1 and 2 once each and nothing missing, got %+v", summaries)
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"cmp"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// Most players a discovery looks at, counting the user, the friends whose friend lists are read and the
+	// friends of friends whose libraries are compared. Every one of them is a Steam request when it isn't cached
+	maxDiscoveryNodes = 250
+	// Half of the nodes go to reading friend lists and the rest to comparing libraries, so a big friend list
+	// can't use up the whole budget and leave nobody to suggest
+	maxExpandedFriends     = maxDiscoveryNodes / 2
+	maxDiscoveryCandidates = maxDiscoveryNodes - 1 - maxExpandedFriends
+
+	// A discovery can take a few minutes of Steam requests, so only this many run at once across every caller
+	maxConcurrentDiscoveries = 2
+)
+
+// MutualFriends is how many of the user's friends are friends with the suggested player, FriendID is the
+// suggested player
+type DiscoveredPlayer struct {
+	ComparedMatchedGames
+	MutualFriends int `json:"mutualFriends"`
+}
+
+type Discovery struct {
+	FriendCount     int                `json:"friendCount"`
+	ExpandedFriends int                `json:"expandedFriends"`
+	CandidateCount  int                `json:"candidateCount"`
+	ComparedCount   int                `json:"comparedCount"`
+	Suggestions     []DiscoveredPlayer `json:"suggestions"`
+}
+
+type discoveryCandidate struct {
+	steamID       string
+	mutualFriends int
+}
+
+// Gets the friends-of-friends discovery for a Steam ID from the cache, or runs it when it isn't cached.
+// Every suggestion is returned, callers apply their own limit
+func (apicfg *ApiConfig) GetDiscovery(steamID string, listGames bool) (Discovery, error) {
+	cacheKey := steamID
+	if listGames {
+		cacheKey += ":games"
+	}
+
+	discovery, found := apicfg.DiscoveryCache.ReadCache(cacheKey)
+	if found {
+		log.Printf("Discovery cache found for %s\n", cacheKey)
+		return discovery, nil
+	}
+
+	if apicfg.runningDiscoveries.Add(1) > maxConcurrentDiscoveries {
+		apicfg.runningDiscoveries.Add(-1)
+		return Discovery{}, NewError(http.StatusTooManyRequests, CodeRateLimited, "Too many discoveries are running, please try again in a few minutes", nil)
+	}
+	defer apicfg.runningDiscoveries.Add(-1)
+
+	ownedGames, err := apicfg.GetOwnedGames(steamID)
+	if err != nil {
+		return Discovery{}, NewError(http.StatusInternalServerError, CodeUpstream, "Unable to perform API calls to Steam GetOwnedGames endpoint", err)
+	}
+
+	friendList, err := apicfg.GetFriendList(steamID)
+	if err != nil {
+		return Discovery{}, NewError(http.StatusInternalServerError, CodeUpstream, "Unable to perform API calls to Steam GetFriendList endpoint", err)
+	}
+
+	discovery = apicfg.DiscoverFriendsOfFriends(ownedGames, friendList.Friends, listGames)
+	apicfg.DiscoveryCache.UpdateCache(cacheKey, discovery)
+
+	return discovery, nil
+}
+
+// Looks at the friends of the user's friends and suggests the ones that aren't friends yet, ranked with the same
+// score as the matched games ranking. Friend lists and libraries are fetched paced the same way too, and friends
+// with private friend lists or libraries are skipped
+func (apicfg *ApiConfig) DiscoverFriendsOfFriends(ownedGames OwnedGames, friends []Friend, listGames bool) Discovery {
+	expanded := friends[:min(len(friends), maxExpandedFriends)]
+	friendLists := apicfg.friendListsOf(expanded)
+
+	candidates, candidateCount := buildDiscoveryCandidates(ownedGames.SteamID, friends, friendLists, maxDiscoveryCandidates)
+
+	discovery := Discovery{
+		FriendCount:     len(friends),
+		ExpandedFriends: len(friendLists),
+		CandidateCount:  candidateCount,
+		ComparedCount:   len(candidates),
+		Suggestions:     []DiscoveredPlayer{},
+	}
+	if len(candidates) == 0 {
+		return discovery
+	}
+
+	candidateFriends := make([]Friend, 0, len(candidates))
+	mutualFriends := make(map[string]int, len(candidates))
+	for _, candidate := range candidates {
+		candidateFriends = append(candidateFriends, Friend{SteamID: candidate.steamID})
+		mutualFriends[candidate.steamID] = candidate.mutualFriends
+	}
+
+	comparisons := apicfg.compareWithFriends(ownedGames, candidateFriends, listGames)
+	discovery.Suggestions = rankDiscoveredPlayers(comparisons, mutualFriends)
+
+	return discovery
+}
+
+// Gets the friend list of every friend, cached friend lists don't wait for the ticker since they don't call Steam
+func (apicfg *ApiConfig) friendListsOf(friends []Friend) map[string]FriendList {
+	friendLists := make(map[string]FriendList, len(friends))
+	if len(friends) == 0 {
+		return friendLists
+	}
+
+	ticker := time.NewTicker(friendRequestInterval(len(friends)))
+	defer ticker.Stop()
+
+	for _, friend := range friends {
+		if _, found := apicfg.FriendListCache.ReadCache(friend.SteamID); !found {
+			<-ticker.C
+		}
+		friendList, err := apicfg.GetFriendList(friend.SteamID)
+		if err != nil {
+			log.Printf("Error getting friend list for %s: %v", friend.SteamID, err)
+			continue
+		}
+		friendLists[friend.SteamID] = friendList
+	}
+
+	return friendLists
+}
+
+// Helper function that counts how many of the user's friends know each player that isn't the user or one of
+// their friends. Only the maxCandidates players with the most mutual friends are kept, the total found is
+// returned alongside them
+func buildDiscoveryCandidates(userID string, friends []Friend, friendLists map[string]FriendList, maxCandidates int) ([]discoveryCandidate, int) {
+	known := map[string]bool{userID: true}
+	for _, friend := range friends {
+		known[friend.SteamID] = true
+	}
+
+	mutualFriends := map[string]int{}
+	for _, friendList := range friendLists {
+		for _, friendOfFriend := range friendList.Friends {
+			if known[friendOfFriend.SteamID] {
+				continue
+			}
+			mutualFriends[friendOfFriend.SteamID]++
+		}
+	}
+
+	candidates := make([]discoveryCandidate, 0, len(mutualFriends))
+	for steamID, count := range mutualFriends {
+		candidates = append(candidates, discoveryCandidate{steamID: steamID, mutualFriends: count})
+	}
+	slices.SortFunc(candidates, func(a, b discoveryCandidate) int {
+		if a.mutualFriends != b.mutualFriends {
+			return cmp.Compare(b.mutualFriends, a.mutualFriends)
+		}
+		return cmp.Compare(a.steamID, b.steamID)
+	})
+
+	return candidates[:min(len(candidates), max(maxCandidates, 0))], len(candidates)
+}
+
+// Helper function that ranks compared players by score and then by mutual friends. Players whose library
+// couldn't be read or who share no games with the user aren't suggested
+func rankDiscoveredPlayers(comparisons []ComparedMatchedGames, mutualFriends map[string]int) []DiscoveredPlayer {
+	suggestions := []DiscoveredPlayer{}
+	for _, comparison := range comparisons {
+		if comparison.FriendID == "" || comparison.Matches == 0 {
+			continue
+		}
+		suggestions = append(suggestions, DiscoveredPlayer{
+			ComparedMatchedGames: comparison,
+			MutualFriends:        mutualFriends[comparison.FriendID],
+		})
+	}
+
+	slices.SortFunc(suggestions, func(a, b DiscoveredPlayer) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		if a.MutualFriends != b.MutualFriends {
+			return cmp.Compare(b.MutualFriends, a.MutualFriends)
+		}
+		return cmp.Compare(a.FriendID, b.FriendID)
+	})
+
+	for i := range suggestions {
+		suggestions[i].Ranking = i + 1
+	}
+
+	return suggestions
+}
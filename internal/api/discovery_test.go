@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBuildDiscoveryCandidates(t *testing.T) {
+	friends := []Friend{{SteamID: "10"}, {SteamID: "11"}, {SteamID: "12"}}
+	friendLists := map[string]FriendList{
+		"10": {Friends: []Friend{{SteamID: "1"}, {SteamID: "11"}, {SteamID: "20"}, {SteamID: "21"}}},
+		"11": {Friends: []Friend{{SteamID: "1"}, {SteamID: "10"}, {SteamID: "20"}, {SteamID: "22"}}},
+		"12": {Friends: []Friend{{SteamID: "20"}, {SteamID: "21"}}},
+	}
+
+	candidates, total := buildDiscoveryCandidates("1", friends, friendLists, 2)
+
+	if total != 3 {
+		t.Errorf("expected 3 friends of friends, got %d", total)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("expected the candidates to be capped at 2, got %v", candidates)
+	}
+	if candidates[0] != (discoveryCandidate{steamID: "20", mutualFriends: 3}) || candidates[1] != (discoveryCandidate{steamID: "21", mutualFriends: 2}) {
+		t.Errorf("expected Steam IDs 20 and 21 with the most mutual friends, got %v", candidates)
+	}
+
+	if none, _ := buildDiscoveryCandidates("1", friends, friendLists, -1); len(none) != 0 {
+		t.Errorf("expected no candidates once the node cap is used up, got %v", none)
+	}
+}
+
+func TestRankDiscoveredPlayers(t *testing.T) {
+	user := OwnedGames{SteamID: "1", GameCount: 3, Games: []Game{{AppID: 1}, {AppID: 2}, {AppID: 3}}}
+	comparisons := []ComparedMatchedGames{
+		user.CompareOwnedGames(OwnedGames{SteamID: "20", GameCount: 2, Games: []Game{{AppID: 1}, {AppID: 4}}}, false),
+		user.CompareOwnedGames(OwnedGames{SteamID: "21", GameCount: 2, Games: []Game{{AppID: 1}, {AppID: 2}}}, false),
+		user.CompareOwnedGames(OwnedGames{SteamID: "22", GameCount: 1, Games: []Game{{AppID: 5}}}, false),
+		// A private library is left as an empty comparison
+		{},
+	}
+	mutualFriends := map[string]int{"20": 3, "21": 1, "22": 5}
+
+	suggestions := rankDiscoveredPlayers(comparisons, mutualFriends)
+
+	if len(suggestions) != 2 {
+		t.Fatalf("expected players sharing no games to be left out, got %v", suggestions)
+	}
+	if suggestions[0].FriendID != "21" || suggestions[0].Ranking != 1 || suggestions[0].MutualFriends != 1 {
+		t.Errorf("expected Steam ID 21 ranked first by score, got %+v", suggestions[0])
+	}
+	if suggestions[1].FriendID != "20" || suggestions[1].MutualFriends != 3 {
+		t.Errorf("expected Steam ID 20 second with 3 mutual friends, got %+v", suggestions[1])
+	}
+}
+
+func TestGetDiscoveryLimitsConcurrentRuns(t *testing.T) {
+	apicfg := &ApiConfig{
+		DiscoveryCache: Cache[Discovery]{Cache: map[string]CachedData[Discovery]{}, RenewTime: time.Hour},
+	}
+	apicfg.DiscoveryCache.UpdateCache("1", Discovery{FriendCount: 3})
+	apicfg.runningDiscoveries.Store(maxConcurrentDiscoveries)
+
+	cached, err := apicfg.GetDiscovery("1", false)
+	if err != nil || cached.FriendCount != 3 {
+		t.Errorf("expected the cached discovery even while others run, got %+v and %v", cached, err)
+	}
+
+	_, err = apicfg.GetDiscovery("2", false)
+	var appErr *Error
+	if !errors.As(err, &appErr) || appErr.Status != http.StatusTooManyRequests {
+		t.Errorf("expected a 429 once too many discoveries run, got %v", err)
+	}
+	if apicfg.runningDiscoveries.Load() != maxConcurrentDiscoveries {
+		t.Errorf("expected the rejected run to give its slot back, got %d running", apicfg.runningDiscoveries.Load())
+	}
+}
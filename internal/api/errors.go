@@ -0,0 +1,126 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// Stable machine-readable error codes, clients can rely on these not changing even when messages do
+const (
+	CodeBadRequest         = "bad_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeForbidden          = "forbidden"
+	CodeCSRFFailed         = "csrf_failed"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeUsernameTaken      = "username_taken"
+	CodeEmailTaken         = "email_taken"
+	CodePreconditionFailed = "precondition_failed"
+	CodeRateLimited        = "rate_limited"
+	CodeInternal           = "internal_error"
+	CodeUpstream           = "upstream_error"
+)
+
+// Postgres error codes that get mapped to client errors, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqCheckViolation      = "23514"
+	pqInvalidText         = "22P02"
+)
+
+// Error is an application error that knows how it should be reported to the client.
+// Err is the underlying cause, it's logged but never sent in the response
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NewError(status int, code, message string, err error) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// Maps an error from a database call to an application error, so a unique violation on the username
+// says the username is taken instead of a generic failure. fallbackMessage is used for anything unexpected
+func DatabaseError(err error, fallbackMessage string) *Error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return NewError(http.StatusNotFound, CodeNotFound, "Not found", err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqUniqueViolation:
+			switch pqErr.Constraint {
+			case "users_username_key":
+				return NewError(http.StatusConflict, CodeUsernameTaken, "That username is already taken", err)
+			case "users_verified_email_key":
+				return NewError(http.StatusConflict, CodeEmailTaken, "That email is already in use", err)
+			case "squads_user_id_name_key":
+				return NewError(http.StatusConflict, CodeConflict, "You already have a squad with that name", err)
+			}
+			return NewError(http.StatusConflict, CodeConflict, "That already exists", err)
+		case pqForeignKeyViolation:
+			return NewError(http.StatusConflict, CodeConflict, "A related record does not exist", err)
+		case pqCheckViolation:
+			return NewError(http.StatusUnprocessableEntity, CodeValidationFailed, "A value is not allowed", err)
+		case pqInvalidText:
+			return NewError(http.StatusBadRequest, CodeBadRequest, "A value has the wrong format", err)
+		}
+	}
+
+	return NewError(http.StatusInternalServerError, CodeInternal, fallbackMessage, err)
+}
+
+// Default code for responses that only give a status
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed, http.StatusPreconditionRequired:
+		return CodePreconditionFailed
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeUpstream
+	}
+
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestDatabaseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"username taken", &pq.Error{Code: "23505", Constraint: "users_username_key"}, http.StatusConflict, CodeUsernameTaken},
+		{"email taken", &pq.Error{Code: "23505", Constraint: "users_verified_email_key"}, http.StatusConflict, CodeEmailTaken},
+		{"other unique violation", &pq.Error{Code: "23505", Constraint: "something_else"}, http.StatusConflict, CodeConflict},
+		{"wrapped unique violation", fmt.Errorf("create user: %w", &pq.Error{Code: "23505", Constraint: "users_username_key"}), http.StatusConflict, CodeUsernameTaken},
+		{"check violation", &pq.Error{Code: "23514"}, http.StatusUnprocessableEntity, CodeValidationFailed},
+		{"no rows", sql.ErrNoRows, http.StatusNotFound, CodeNotFound},
+		{"unknown", errors.New("connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DatabaseError(tt.err, "fallback")
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode {
+				t.Errorf("DatabaseError() = %d %s, want %d %s", got.Status, got.Code, tt.wantStatus, tt.wantCode)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("DatabaseError() should wrap the original error")
+			}
+		})
+	}
+}
+
+func TestRespondWithErrorEnvelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(RequestIDHeader, "req-123")
+
+	RespondWithError(w, http.StatusTooManyRequests, "Slow down", nil)
+
+	var body errorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("couldn't decode response: %v", err)
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if body.Code != CodeRateLimited || body.Message != "Slow down" || body.Error != "Slow down" || body.RequestID != "req-123" {
+		t.Errorf("unexpected envelope: %+v", body)
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// How many uncached apps a single request may look up in the store before answering with what it has
+const requestAppDetailsBudget = 10
+
+// Whether a request with these query parameters may look up store details, which can take a while since
+// store requests are spaced out
+func UsesStoreDetails(query url.Values) bool {
+	filter, err := ParseGameFilter(query)
+	return (err == nil && filter.Active()) || query.Get("mode") == rankingModeTaste
+}
+
+// Filters on store metadata, games without metadata yet never match an active filter
+type GameFilter struct {
+	MultiplayerOnly bool
+	Coop            bool
+	Genre           string
+}
+
+// Reads the multiplayerOnly, coop and genre query parameters
+func ParseGameFilter(query url.Values) (GameFilter, error) {
+	filter := GameFilter{
+		Genre: strings.TrimSpace(query.Get("genre")),
+	}
+
+	flags := []struct {
+		name   string
+		target *bool
+	}{
+		{"multiplayerOnly", &filter.MultiplayerOnly},
+		{"coop", &filter.Coop},
+	}
+	for _, flag := range flags {
+		value := query.Get(flag.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return GameFilter{}, NewError(http.StatusBadRequest, CodeBadRequest, "'"+flag.name+"' parameter must be true or false", err)
+		}
+		*flag.target = parsed
+	}
+
+	return filter, nil
+}
+
+func (filter GameFilter) Active() bool {
+	return filter.MultiplayerOnly || filter.Coop || filter.Genre != ""
+}
+
+func (filter GameFilter) Matches(details AppDetails) bool {
+	if !details.Found {
+		return false
+	}
+	if filter.MultiplayerOnly && !details.Multiplayer {
+		return false
+	}
+	if filter.Coop && !details.Coop {
+		return false
+	}
+	if filter.Genre != "" {
+		for _, genre := range details.Genres {
+			if strings.EqualFold(genre, filter.Genre) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Keeps the games matching the filter, pending counts games left out only because their metadata isn't known yet
+func (apicfg *ApiConfig) FilterGames(ctx context.Context, games []Game, filter GameFilter, fetchBudget int) ([]Game, int, error) {
+	appIDs := make([]int, 0, len(games))
+	for _, game := range games {
+		appIDs = append(appIDs, game.AppID)
+	}
+
+	details, err := apicfg.GetAppDetails(ctx, appIDs, fetchBudget)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filtered := []Game{}
+	pending := 0
+	for _, game := range games {
+		appDetails, found := details[game.AppID]
+		if !found {
+			pending++
+			continue
+		}
+		if filter.Matches(appDetails) {
+			filtered = append(filtered, game)
+		}
+	}
+
+	return filtered, pending, nil
+}
+
+// Narrows a comparison down to the user's games that passed the filter and works out the score again.
+// Friend only games can't be fetched for every friend, so only ones with known metadata are kept
+func (result *ComparedMatchedGames) applyFilter(allowed map[int]bool, userGameCount int, filter GameFilter, known map[int]AppDetails) {
+	matching := []Game{}
+	for _, game := range result.MatchingGames {
+		if allowed[game.AppID] {
+			matching = append(matching, game)
+		}
+	}
+	result.MatchingGames = matching
+	result.Matches = len(matching)
+
+	friendOnly := []Game{}
+	for _, game := range result.FriendOnlyGames {
+		if details, found := known[game.AppID]; found && filter.Matches(details) {
+			friendOnly = append(friendOnly, game)
+		}
+	}
+	result.FriendOnlyGames = friendOnly
+
+	result.UserPercentage = 0
+	if userGameCount > 0 {
+		result.UserPercentage = float64(result.Matches) / float64(userGameCount)
+	}
+	result.FriendPercentage = 0
+	if result.FriendGamesCount > 0 {
+		result.FriendPercentage = float64(result.Matches) / float64(result.FriendGamesCount)
+	}
+	result.Score = comparisonScore(result.Matches, result.FriendPercentage)
+}
+
+// Store details already known for the friend only games of every comparison, nothing is fetched from Steam
+func (apicfg *ApiConfig) knownFriendOnlyDetails(ctx context.Context, comparisons []ComparedMatchedGames) (map[int]AppDetails, error) {
+	appIDs := []int{}
+	for _, comparison := range comparisons {
+		for _, game := range comparison.FriendOnlyGames {
+			appIDs = append(appIDs, game.AppID)
+		}
+	}
+	return apicfg.GetAppDetails(ctx, appIDs, 0)
+}
+
+func (apicfg *ApiConfig) filterRecommendations(ctx context.Context, recommendations []Recommendation, filter GameFilter) ([]Recommendation, int, error) {
+	appIDs := make([]int, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		appIDs = append(appIDs, recommendation.AppID)
+	}
+
+	details, err := apicfg.GetAppDetails(ctx, appIDs, requestAppDetailsBudget)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filtered := []Recommendation{}
+	pending := 0
+	for _, recommendation := range recommendations {
+		appDetails, found := details[recommendation.AppID]
+		if !found {
+			pending++
+			continue
+		}
+		if filter.Matches(appDetails) {
+			filtered = append(filtered, recommendation)
+		}
+	}
+
+	return filtered, pending, nil
+}
@@ -4,18 +4,45 @@ import (
 	"log"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	rankingModeGames = "games"
+	rankingModeTaste = "taste"
+
+	defaultRecommendationLimit = 20
+	maxRecommendationLimit     = 100
+
+	defaultDiscoveryLimit = 20
+	maxDiscoveryLimit     = 100
+)
+
 type ApiConfig struct {
 	SteamApiKey string
-
-	PlayerCache       Cache[Player]
-	FriendListCache   Cache[FriendList]
-	OwnedGamesCache   Cache[OwnedGames]
-	AchievementsCache Cache[ConvertedPlayerAchievements]
+	StoreAPIURL string
+
+	// Optional, app details are only kept in memory without it
+	AppDetailsStore AppDetailsStore
+
+	PlayerCache         Cache[Player]
+	FriendListCache     Cache[FriendList]
+	OwnedGamesCache     Cache[OwnedGames]
+	AchievementsCache   Cache[ConvertedPlayerAchievements]
+	RecentlyPlayedCache Cache[RecentlyPlayedGames]
+	AppDetailsCache     Cache[AppDetails]
+	BansCache           Cache[PlayerBans]
+	LevelCache          Cache[int]
+	BadgesCache         Cache[Badges]
+	PresenceCache       Cache[Player]
+	DiscoveryCache      Cache[Discovery]
+
+	runningDiscoveries atomic.Int32
+	storeRequests      requestSpacer
 }
 
 func (apicfg *ApiConfig) HandlerGetPlayerSummaries(w http.ResponseWriter, req *http.Request) {
@@ -25,12 +52,24 @@ func (apicfg *ApiConfig) HandlerGetPlayerSummaries(w http.ResponseWriter, req *h
 		return
 	}
 
+	includes, err := ParsePlayerIncludes(req.URL.Query().Get("include"))
+	if err != nil {
+		RespondWithAppError(w, err)
+		return
+	}
+
 	playerSummaries, err := apicfg.GetPlayerSummaries(strings.Split(steamIDs, ","))
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API call to Steam GetPlayerSummaries endpoint", err)
 		return
 	}
 
+	err = apicfg.AddPlayerDetails(playerSummaries.Players, includes)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to get player details from Steam", err)
+		return
+	}
+
 	RespondWithJSON(w, http.StatusOK, playerSummaries)
 }
 
@@ -41,6 +80,12 @@ func (apicfg *ApiConfig) HandlerGetFriendList(w http.ResponseWriter, req *http.R
 		return
 	}
 
+	includes, err := ParsePlayerIncludes(req.URL.Query().Get("include"))
+	if err != nil {
+		RespondWithAppError(w, err)
+		return
+	}
+
 	friends, err := apicfg.GetFriendList(steamID)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API call to Steam GetFriendList endpoint", err)
@@ -58,6 +103,12 @@ func (apicfg *ApiConfig) HandlerGetFriendList(w http.ResponseWriter, req *http.R
 		return
 	}
 
+	err = apicfg.AddPlayerDetails(summaries.Players, includes)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to get player details from Steam", err)
+		return
+	}
+
 	RespondWithJSON(w, http.StatusOK, summaries)
 }
 
@@ -68,13 +119,55 @@ func (apicfg *ApiConfig) HandlerGetOwnedGames(w http.ResponseWriter, req *http.R
 		return
 	}
 
+	filter, err := ParseGameFilter(req.URL.Query())
+	if err != nil {
+		RespondWithAppError(w, err)
+		return
+	}
+
 	ownedGames, err := apicfg.GetOwnedGames(steamID)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API call to Steam GetOwnedGames endpoint", err)
 		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, ownedGames)
+	if !filter.Active() {
+		RespondWithJSON(w, http.StatusOK, ownedGames)
+		return
+	}
+
+	// The cached library is shared, so the filtered games go into a copy
+	filteredGames, pending, err := apicfg.FilterGames(req.Context(), ownedGames.Games, filter, requestAppDetailsBudget)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to get store details of games", err)
+		return
+	}
+	ownedGames.Games = filteredGames
+	ownedGames.GameCount = len(filteredGames)
+
+	RespondWithJSON(w, http.StatusOK, struct {
+		OwnedGames
+		MetadataPending int `json:"metadataPending"`
+	}{
+		OwnedGames:      ownedGames,
+		MetadataPending: pending,
+	})
+}
+
+func (apicfg *ApiConfig) HandlerGetRecentlyPlayedGames(w http.ResponseWriter, req *http.Request) {
+	steamID := req.URL.Query().Get("steamID")
+	if steamID == "" {
+		RespondWithError(w, http.StatusBadRequest, "'steamid' parameter is required for getting recently played games", nil)
+		return
+	}
+
+	recentGames, err := apicfg.GetRecentlyPlayedGames(steamID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API call to Steam GetRecentlyPlayedGames endpoint", err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, recentGames)
 }
 
 func (apicfg *ApiConfig) HandlerGetPlayerAchievements(w http.ResponseWriter, req *http.Request) {
@@ -144,31 +237,272 @@ func (apicfg *ApiConfig) HandlerMatchedGamesRanking(w http.ResponseWriter, req *
 		listGames = true
 	}
 
+	filter, err := ParseGameFilter(req.URL.Query())
+	if err != nil {
+		RespondWithAppError(w, err)
+		return
+	}
+
+	// "games" ranks friends by how many games they have in common, "taste" by how alike the genres they play are
+	mode := req.URL.Query().Get("mode")
+	if mode == "" {
+		mode = rankingModeGames
+	}
+	if mode != rankingModeGames && mode != rankingModeTaste {
+		RespondWithError(w, http.StatusBadRequest, "'mode' parameter must be games or taste", nil)
+		return
+	}
+
 	ownedGames, err := apicfg.GetOwnedGames(steamid)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API calls to Steam GetOwnedGames endpoint", err)
 		return
 	}
 
+	// Only the user's games are looked up in the store, a match is only counted when it passes the filter
+	allowed := map[int]bool{}
+	filteredCount := 0
+	pending := 0
+	if filter.Active() {
+		filteredGames, pendingGames, err := apicfg.FilterGames(req.Context(), ownedGames.Games, filter, requestAppDetailsBudget)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Unable to get store details of games", err)
+			return
+		}
+		for _, game := range filteredGames {
+			allowed[game.AppID] = true
+		}
+		filteredCount = len(filteredGames)
+		pending = pendingGames
+	}
+
 	friendList, err := apicfg.GetFriendList(steamid)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API calls to Steam GetFriendList endpoint", err)
 		return
 	}
 
-	type job struct {
-		friend Friend
-		idx    int
-	}
-
-	friendCount := len(friendList.Friends)
-	if friendCount == 0 {
+	if len(friendList.Friends) == 0 {
 		RespondWithJSON(w, http.StatusOK, struct {
 			Ranking []ComparedMatchedGames `json:"ranking"`
 		}{Ranking: []ComparedMatchedGames{}})
 		return
 	}
 
+	var userTaste TasteProfile
+	if mode == rankingModeTaste {
+		userTaste, err = apicfg.TasteProfile(req.Context(), ownedGames, requestAppDetailsBudget)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Unable to get store details of games", err)
+			return
+		}
+	}
+
+	friendTastes := make(map[string]TasteProfile, len(friendList.Friends))
+	tasteMutex := sync.Mutex{}
+	results := apicfg.compareWithFriendsFunc(friendList.Friends, func(friendGames OwnedGames) ComparedMatchedGames {
+		result := ownedGames.CompareOwnedGames(friendGames, listGames || filter.Active())
+		if mode == rankingModeTaste {
+			// Friends' games aren't looked up in the store here, that would take minutes for a big friend list
+			friendTaste, err := apicfg.TasteProfile(req.Context(), friendGames, 0)
+			if err != nil {
+				log.Printf("Error getting taste profile for %s: %v", friendGames.SteamID, err)
+			}
+			tasteMutex.Lock()
+			friendTastes[friendGames.SteamID] = friendTaste
+			tasteMutex.Unlock()
+		}
+		return result
+	})
+	if filter.Active() {
+		known, err := apicfg.knownFriendOnlyDetails(req.Context(), results)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Unable to get store details of games", err)
+			return
+		}
+		for i := range results {
+			if results[i].FriendID == "" {
+				continue
+			}
+			results[i].applyFilter(allowed, filteredCount, filter, known)
+			if !listGames {
+				results[i].MatchingGames = nil
+				results[i].FriendOnlyGames = nil
+			}
+		}
+	}
+
+	if mode == rankingModeTaste {
+		for i := range results {
+			if results[i].FriendID == "" {
+				continue
+			}
+			similarity, shared := CompareTaste(userTaste, friendTastes[results[i].FriendID])
+			results[i].TasteSimilarity = similarity
+			results[i].SharedGenres = shared
+			results[i].Score = similarity * 100
+		}
+	}
+
+	// Sort results by player's score in descending order
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	for i := range results {
+		results[i].Ranking = i + 1
+	}
+
+	resp := struct {
+		Ranking         []ComparedMatchedGames `json:"ranking"`
+		MetadataPending int                    `json:"metadataPending,omitempty"`
+	}{
+		Ranking:         results,
+		MetadataPending: pending,
+	}
+
+	RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// Shows which games a user's friends have been playing over the last two weeks, grouped by game
+func (apicfg *ApiConfig) HandlerFriendActivity(w http.ResponseWriter, req *http.Request) {
+	steamID := req.URL.Query().Get("steamID")
+	if steamID == "" {
+		RespondWithError(w, http.StatusBadRequest, "'steamid' parameter is required for getting friend activity", nil)
+		return
+	}
+
+	friendList, err := apicfg.GetFriendList(steamID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API calls to Steam GetFriendList endpoint", err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, apicfg.FriendActivity(friendList.Friends))
+}
+
+// Lists which friends are online right now, grouped by the game they're in and otherwise by presence
+func (apicfg *ApiConfig) HandlerOnlineFriends(w http.ResponseWriter, req *http.Request) {
+	steamID := req.URL.Query().Get("steamID")
+	if steamID == "" {
+		RespondWithError(w, http.StatusBadRequest, "'steamid' parameter is required for getting online friends", nil)
+		return
+	}
+
+	friendList, err := apicfg.GetFriendList(steamID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API calls to Steam GetFriendList endpoint", err)
+		return
+	}
+
+	friendIDs := []string{}
+	for _, friend := range friendList.Friends {
+		friendIDs = append(friendIDs, friend.SteamID)
+	}
+
+	players, err := apicfg.GetPresence(friendIDs)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API call to Steam GetPlayerSummaries endpoint", err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, buildOnlineFriends(friendIDs, players))
+}
+
+// Ranks games the user's friends play a lot that the user doesn't own, along with the friends behind each one
+func (apicfg *ApiConfig) HandlerFriendRecommendations(w http.ResponseWriter, req *http.Request) {
+	steamID := req.URL.Query().Get("steamID")
+	if steamID == "" {
+		RespondWithError(w, http.StatusBadRequest, "'steamid' parameter is required for getting recommendations", nil)
+		return
+	}
+
+	limit := defaultRecommendationLimit
+	if limitQuery := req.URL.Query().Get("limit"); limitQuery != "" {
+		parsed, err := strconv.Atoi(limitQuery)
+		if err != nil || parsed <= 0 {
+			RespondWithError(w, http.StatusBadRequest, "'limit' parameter must be a positive number", err)
+			return
+		}
+		limit = min(parsed, maxRecommendationLimit)
+	}
+
+	filter, err := ParseGameFilter(req.URL.Query())
+	if err != nil {
+		RespondWithAppError(w, err)
+		return
+	}
+
+	ownedGames, err := apicfg.GetOwnedGames(steamID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API calls to Steam GetOwnedGames endpoint", err)
+		return
+	}
+
+	friendList, err := apicfg.GetFriendList(steamID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API calls to Steam GetFriendList endpoint", err)
+		return
+	}
+
+	recommendations := []Recommendation{}
+	pending := 0
+	if len(friendList.Friends) > 0 {
+		comparisons := apicfg.compareWithFriends(ownedGames, friendList.Friends, true)
+		if !filter.Active() {
+			recommendations = RecommendGames(comparisons, limit)
+		} else {
+			recommendations, pending, err = apicfg.filterRecommendations(req.Context(), RecommendGames(comparisons, maxRecommendationLimit), filter)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, "Unable to get store details of games", err)
+				return
+			}
+			recommendations = recommendations[:min(limit, len(recommendations))]
+		}
+	}
+
+	RespondWithJSON(w, http.StatusOK, struct {
+		Recommendations []Recommendation `json:"recommendations"`
+		MetadataPending int              `json:"metadataPending,omitempty"`
+	}{
+		Recommendations: recommendations,
+		MetadataPending: pending,
+	})
+}
+
+// Suggests players the user isn't friends with yet from their friends' friend lists, ranked by the games they share
+func (apicfg *ApiConfig) HandlerDiscoverFriends(w http.ResponseWriter, req *http.Request) {
+	steamID := req.URL.Query().Get("steamID")
+	if steamID == "" {
+		RespondWithError(w, http.StatusBadRequest, "'steamid' parameter is required for discovering players", nil)
+		return
+	}
+
+	limit := defaultDiscoveryLimit
+	if limitQuery := req.URL.Query().Get("limit"); limitQuery != "" {
+		parsed, err := strconv.Atoi(limitQuery)
+		if err != nil || parsed <= 0 {
+			RespondWithError(w, http.StatusBadRequest, "'limit' parameter must be a positive number", err)
+			return
+		}
+		limit = min(parsed, maxDiscoveryLimit)
+	}
+
+	listGames := req.URL.Query().Get("listGames") == "true"
+
+	discovery, err := apicfg.GetDiscovery(steamID, listGames)
+	if err != nil {
+		RespondWithAppError(w, err)
+		return
+	}
+
+	discovery.Suggestions = discovery.Suggestions[:min(len(discovery.Suggestions), limit)]
+	RespondWithJSON(w, http.StatusOK, discovery)
+}
+
+// Spreads requests made for every friend over a few seconds, more friends get a longer window so we don't
+// get 429 errors from Steam
+func friendRequestInterval(friendCount int) time.Duration {
 	var waitTime int
 	if friendCount > 100 {
 		waitTime = 20
@@ -178,14 +512,33 @@ func (apicfg *ApiConfig) HandlerMatchedGamesRanking(w http.ResponseWriter, req *
 		waitTime = 5
 	}
 
+	return time.Duration(float64(waitTime) / float64(friendCount) * float64(time.Second))
+}
+
+// Compares a user's games with every friend's, results are in the same order as friends. Friends whose games
+// couldn't be read are left as empty comparisons
+func (apicfg *ApiConfig) compareWithFriends(ownedGames OwnedGames, friends []Friend, listGames bool) []ComparedMatchedGames {
+	return apicfg.compareWithFriendsFunc(friends, func(friendGames OwnedGames) ComparedMatchedGames {
+		return ownedGames.CompareOwnedGames(friendGames, listGames)
+	})
+}
+
+// Same as compareWithFriends but with a custom comparison, which gets every friend's library in turn
+func (apicfg *ApiConfig) compareWithFriendsFunc(friends []Friend, compare func(friendGames OwnedGames) ComparedMatchedGames) []ComparedMatchedGames {
+	type job struct {
+		friend Friend
+		idx    int
+	}
+
+	friendCount := len(friends)
+
 	// Use a worker pool (currently 1 worker) to make all API calls over an interval
 	// that is determined by how many friends a user has, this is to help prevent 429 errors from Steam's end
 	jobs := make(chan job, friendCount)
 	results := make([]ComparedMatchedGames, friendCount)
 	waitGroup := sync.WaitGroup{}
 
-	interval := time.Duration(float64(waitTime) / float64(friendCount) * float64(time.Second))
-	ticker := time.NewTicker(interval)
+	ticker := time.NewTicker(friendRequestInterval(friendCount))
 	defer ticker.Stop()
 
 	worker := func() {
@@ -197,8 +550,7 @@ func (apicfg *ApiConfig) HandlerMatchedGamesRanking(w http.ResponseWriter, req *
 				waitGroup.Done()
 				continue
 			}
-			result := ownedGames.CompareOwnedGames(friendGames, listGames)
-			results[i.idx] = result
+			results[i.idx] = compare(friendGames)
 			waitGroup.Done()
 		}
 	}
@@ -206,27 +558,34 @@ func (apicfg *ApiConfig) HandlerMatchedGamesRanking(w http.ResponseWriter, req *
 	go worker()
 
 	// Queue up jobs
-	for idx, friend := range friendList.Friends {
+	for idx, friend := range friends {
 		waitGroup.Add(1)
 		jobs <- job{friend: friend, idx: idx}
 	}
 	close(jobs)
 	waitGroup.Wait()
 
-	// Sort results by player's score in descending order
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
+	return results
+}
 
-	for i := range results {
-		results[i].Ranking = i + 1
+func (apicfg *ApiConfig) HandlerGetTasteProfile(w http.ResponseWriter, req *http.Request) {
+	steamID := req.URL.Query().Get("steamID")
+	if steamID == "" {
+		RespondWithError(w, http.StatusBadRequest, "'steamid' parameter is required for getting a taste profile", nil)
+		return
 	}
 
-	resp := struct {
-		Ranking []ComparedMatchedGames `json:"ranking"`
-	}{
-		Ranking: results,
+	ownedGames, err := apicfg.GetOwnedGames(steamID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to perform API call to Steam GetOwnedGames endpoint", err)
+		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, resp)
+	profile, err := apicfg.TasteProfile(req.Context(), ownedGames, requestAppDetailsBudget)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Unable to get store details of games", err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, profile)
 }
@@ -2,22 +2,49 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 )
 
+// Header the request ID is echoed back in, RespondWithError reads it back from here so error
+// responses can carry the ID without every handler having to pass the request along
+const RequestIDHeader = "X-Request-ID"
+
+type errorResponse struct {
+	// Error is the same as Message, it's kept so existing clients reading "error" keep working
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
 func RespondWithError(w http.ResponseWriter, code int, msg string, logErr error) {
-	if logErr != nil {
-		log.Println(logErr)
+	RespondWithAppError(w, NewError(code, codeForStatus(code), msg, logErr))
+}
+
+// Responds with an application error, anything that isn't an *Error is treated as an internal error
+func RespondWithAppError(w http.ResponseWriter, err error) {
+	appErr := &Error{}
+	if !errors.As(err, &appErr) {
+		appErr = NewError(http.StatusInternalServerError, CodeInternal, "Something went wrong", err)
 	}
-	if code > 499 {
-		log.Printf("Responding with 5XX error: %s", msg)
+
+	requestID := w.Header().Get(RequestIDHeader)
+	if appErr.Err != nil {
+		log.Printf("[%s] %s: %v", requestID, appErr.Code, appErr.Err)
 	}
-	type errorResponse struct {
-		Error string `json:"error"`
+	if appErr.Status > 499 {
+		log.Printf("[%s] Responding with 5XX error: %s", requestID, appErr.Message)
 	}
-	RespondWithJSON(w, code, errorResponse{
-		Error: msg,
+
+	RespondWithJSON(w, appErr.Status, errorResponse{
+		Error:     appErr.Message,
+		Code:      appErr.Code,
+		Message:   appErr.Message,
+		RequestID: requestID,
+		Details:   appErr.Details,
 	})
 }
 
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"cmp"
+	"slices"
+)
+
+type PlaytimeChange struct {
+	AppID  int    `json:"appID"`
+	Name   string `json:"name"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+	Delta  int    `json:"delta"`
+}
+
+type LibraryDiff struct {
+	Added              []Game           `json:"added"`
+	Removed            []Game           `json:"removed"`
+	PlaytimeChanges    []PlaytimeChange `json:"playtimeChanges"`
+	TotalPlaytimeDelta int              `json:"totalPlaytimeDelta"`
+	AddedGamesPlaytime int              `json:"addedGamesPlaytime"`
+}
+
+// Compare two versions of the same library, playtime is in minutes like Steam reports it.
+// Added and removed games are sorted by name and playtime changes by the biggest increase first.
+// An added game may have been played before it was owned (free weekends, family sharing), so its
+// playtime is reported on its own instead of counting towards the delta
+func DiffLibraries(before, after []Game) LibraryDiff {
+	diff := LibraryDiff{
+		Added:           []Game{},
+		Removed:         []Game{},
+		PlaytimeChanges: []PlaytimeChange{},
+	}
+
+	beforeGames := map[int]Game{}
+	for _, game := range before {
+		beforeGames[game.AppID] = game
+	}
+
+	afterGames := map[int]Game{}
+	for _, game := range after {
+		afterGames[game.AppID] = game
+
+		previous, found := beforeGames[game.AppID]
+		if !found {
+			diff.Added = append(diff.Added, game)
+			diff.AddedGamesPlaytime += game.PlaytimeForever
+			continue
+		}
+
+		if game.PlaytimeForever != previous.PlaytimeForever {
+			diff.PlaytimeChanges = append(diff.PlaytimeChanges, PlaytimeChange{
+				AppID:  game.AppID,
+				Name:   game.Name,
+				Before: previous.PlaytimeForever,
+				After:  game.PlaytimeForever,
+				Delta:  game.PlaytimeForever - previous.PlaytimeForever,
+			})
+			diff.TotalPlaytimeDelta += game.PlaytimeForever - previous.PlaytimeForever
+		}
+	}
+
+	for _, game := range before {
+		if _, found := afterGames[game.AppID]; !found {
+			diff.Removed = append(diff.Removed, game)
+		}
+	}
+
+	byName := func(i, j Game) int {
+		return cmp.Or(cmp.Compare(i.Name, j.Name), cmp.Compare(i.AppID, j.AppID))
+	}
+	slices.SortFunc(diff.Added, byName)
+	slices.SortFunc(diff.Removed, byName)
+	slices.SortFunc(diff.PlaytimeChanges, func(i, j PlaytimeChange) int {
+		return cmp.Or(cmp.Compare(j.Delta, i.Delta), cmp.Compare(i.AppID, j.AppID))
+	})
+
+	return diff
+}
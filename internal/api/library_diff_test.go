@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestDiffLibraries(t *testing.T) {
+	before := []Game{
+		{AppID: 400, Name: "Portal", PlaytimeForever: 120},
+		{AppID: 570, Name: "Dota 2", PlaytimeForever: 6000},
+		{AppID: 440, Name: "Team Fortress 2", PlaytimeForever: 300},
+	}
+	after := []Game{
+		{AppID: 400, Name: "Portal", PlaytimeForever: 120},
+		{AppID: 570, Name: "Dota 2", PlaytimeForever: 6600},
+		{AppID: 620, Name: "Portal 2", PlaytimeForever: 45},
+	}
+
+	diff := DiffLibraries(before, after)
+
+	if len(diff.Added) != 1 || diff.Added[0].AppID != 620 {
+		t.Errorf("Added = %v, want Portal 2", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].AppID != 440 {
+		t.Errorf("Removed = %v, want Team Fortress 2", diff.Removed)
+	}
+	if len(diff.PlaytimeChanges) != 1 || diff.PlaytimeChanges[0].Delta != 600 {
+		t.Errorf("PlaytimeChanges = %v, want Dota 2 +600", diff.PlaytimeChanges)
+	}
+	if diff.TotalPlaytimeDelta != 600 {
+		t.Errorf("TotalPlaytimeDelta = %d, want 600", diff.TotalPlaytimeDelta)
+	}
+	if diff.AddedGamesPlaytime != 45 {
+		t.Errorf("AddedGamesPlaytime = %d, want 45", diff.AddedGamesPlaytime)
+	}
+}
+
+func TestDiffLibrariesUnchanged(t *testing.T) {
+	games := []Game{{AppID: 400, Name: "Portal", PlaytimeForever: 120}}
+
+	diff := DiffLibraries(games, games)
+
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 || len(diff.PlaytimeChanges) != 0 || diff.TotalPlaytimeDelta != 0 {
+		t.Errorf("expected an empty diff, got %+v", diff)
+	}
+}
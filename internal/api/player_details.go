@@ -0,0 +1,294 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	IncludeBans   = "bans"
+	IncludeLevel  = "level"
+	IncludeBadges = "badges"
+
+	// Steam's batch endpoints accept at most 100 Steam IDs per request
+	steamIDBatchSize = 100
+)
+
+type PlayerBans struct {
+	SteamID          string `json:"steamID"`
+	CommunityBanned  bool   `json:"communityBanned"`
+	VACBanned        bool   `json:"vacBanned"`
+	NumberOfVACBans  int    `json:"numberOfVACBans"`
+	DaysSinceLastBan int    `json:"daysSinceLastBan"`
+	NumberOfGameBans int    `json:"numberOfGameBans"`
+	EconomyBan       string `json:"economyBan"`
+}
+
+type Badge struct {
+	BadgeID        int `json:"badgeID"`
+	AppID          int `json:"appID,omitempty"`
+	Level          int `json:"level"`
+	CompletionTime int `json:"completionTime"`
+	XP             int `json:"xp"`
+	Scarcity       int `json:"scarcity"`
+}
+
+type Badges struct {
+	PlayerXP          int     `json:"playerXP"`
+	PlayerLevel       int     `json:"playerLevel"`
+	XPNeededToLevelUp int     `json:"xpNeededToLevelUp"`
+	Badges            []Badge `json:"badges"`
+}
+
+// Which extra details to merge into player summaries, read from the include query parameter
+type PlayerIncludes struct {
+	Bans   bool
+	Level  bool
+	Badges bool
+}
+
+func (includes PlayerIncludes) Any() bool {
+	return includes.Bans || includes.Level || includes.Badges
+}
+
+// Levels and badges take a Steam request per player, unlike bans which come in batches
+func (includes PlayerIncludes) PerPlayer() bool {
+	return includes.Level || includes.Badges
+}
+
+// Reads a comma separated list like "bans,level", unknown values are an error so typos don't go unnoticed
+func ParsePlayerIncludes(value string) (PlayerIncludes, error) {
+	includes := PlayerIncludes{}
+	if value == "" {
+		return includes, nil
+	}
+
+	for _, include := range strings.Split(value, ",") {
+		switch strings.TrimSpace(include) {
+		case IncludeBans:
+			includes.Bans = true
+		case IncludeLevel:
+			includes.Level = true
+		case IncludeBadges:
+			includes.Badges = true
+		default:
+			return PlayerIncludes{}, NewError(http.StatusBadRequest, CodeBadRequest, "'include' parameter can only contain bans, level and badges", nil)
+		}
+	}
+
+	return includes, nil
+}
+
+// Adds the requested details to every player. Bans are fetched in batches, levels and badges one player at a
+// time since Steam only takes one Steam ID for those, paced like the other per-friend requests. Players whose
+// level or badges can't be read (private profiles mostly) just go without
+func (apicfg *ApiConfig) AddPlayerDetails(players []Player, includes PlayerIncludes) error {
+	if includes.Bans {
+		steamIDs := make([]string, 0, len(players))
+		for _, player := range players {
+			steamIDs = append(steamIDs, player.SteamID)
+		}
+
+		bans, err := apicfg.GetPlayerBans(steamIDs)
+		if err != nil {
+			return err
+		}
+		for i := range players {
+			if playerBans, found := bans[players[i].SteamID]; found {
+				players[i].Bans = &playerBans
+			}
+		}
+	}
+
+	requestCount := 0
+	if includes.Level {
+		requestCount += len(players)
+	}
+	if includes.Badges {
+		requestCount += len(players)
+	}
+	if requestCount == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(friendRequestInterval(requestCount))
+	defer ticker.Stop()
+
+	// Cached details don't need to wait for the ticker since they don't call Steam
+	for i := range players {
+		player := &players[i]
+
+		if includes.Level {
+			if _, found := apicfg.LevelCache.ReadCache(player.SteamID); !found {
+				<-ticker.C
+			}
+			level, err := apicfg.GetSteamLevel(player.SteamID)
+			if err != nil {
+				log.Printf("Error getting Steam level for %s: %v", player.SteamID, err)
+			} else {
+				player.SteamLevel = &level
+			}
+		}
+
+		if includes.Badges {
+			if _, found := apicfg.BadgesCache.ReadCache(player.SteamID); !found {
+				<-ticker.C
+			}
+			badges, err := apicfg.GetBadges(player.SteamID)
+			if err != nil {
+				log.Printf("Error getting badges for %s: %v", player.SteamID, err)
+			} else {
+				player.Badges = &badges
+			}
+		}
+	}
+
+	return nil
+}
+
+type PlayerBansResponse struct {
+	Players []struct {
+		SteamID          string `json:"SteamId"`
+		CommunityBanned  bool   `json:"CommunityBanned"`
+		VACBanned        bool   `json:"VACBanned"`
+		NumberOfVACBans  int    `json:"NumberOfVACBans"`
+		DaysSinceLastBan int    `json:"DaysSinceLastBan"`
+		NumberOfGameBans int    `json:"NumberOfGameBans"`
+		EconomyBan       string `json:"EconomyBan"`
+	} `json:"players"`
+}
+
+// Make API calls to Steam's GetPlayerBans endpoint for every Steam ID that isn't cached, 100 at a time
+func (apicfg *ApiConfig) GetPlayerBans(steamIDs []string) (map[string]PlayerBans, error) {
+	result := map[string]PlayerBans{}
+	uncachedIDs := []string{}
+	for _, steamID := range steamIDs {
+		if bans, found := apicfg.BansCache.ReadCache(steamID); found {
+			result[steamID] = bans
+		} else {
+			uncachedIDs = append(uncachedIDs, steamID)
+		}
+	}
+
+	for start := 0; start < len(uncachedIDs); start += steamIDBatchSize {
+		batch := uncachedIDs[start:min(start+steamIDBatchSize, len(uncachedIDs))]
+
+		body := PlayerBansResponse{}
+		err := apicfg.getSteamJSON(steamUserURL, "GetPlayerBans", "v1/", url.Values{"steamids": {strings.Join(batch, ",")}}, &body)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, player := range body.Players {
+			bans := PlayerBans(player)
+			apicfg.BansCache.UpdateCache(bans.SteamID, bans)
+			result[bans.SteamID] = bans
+		}
+	}
+
+	return result, nil
+}
+
+type SteamLevelResponse struct {
+	Response struct {
+		PlayerLevel *int `json:"player_level"`
+	} `json:"response"`
+}
+
+// Make API call to Steam's GetSteamLevel endpoint, private profiles don't have a level
+func (apicfg *ApiConfig) GetSteamLevel(steamID string) (int, error) {
+	if level, found := apicfg.LevelCache.ReadCache(steamID); found {
+		return level, nil
+	}
+
+	body := SteamLevelResponse{}
+	err := apicfg.getSteamJSON(steamPlayerURL, "GetSteamLevel", "v1/", url.Values{"steamid": {steamID}}, &body)
+	if err != nil {
+		return 0, err
+	}
+	if body.Response.PlayerLevel == nil {
+		return 0, errors.New("steam level is private")
+	}
+
+	apicfg.LevelCache.UpdateCache(steamID, *body.Response.PlayerLevel)
+
+	return *body.Response.PlayerLevel, nil
+}
+
+type BadgesResponse struct {
+	Response struct {
+		Badges []struct {
+			BadgeID        int `json:"badgeid"`
+			AppID          int `json:"appid"`
+			Level          int `json:"level"`
+			CompletionTime int `json:"completion_time"`
+			XP             int `json:"xp"`
+			Scarcity       int `json:"scarcity"`
+		} `json:"badges"`
+		PlayerXP          int `json:"player_xp"`
+		PlayerLevel       int `json:"player_level"`
+		XPNeededToLevelUp int `json:"player_xp_needed_to_level_up"`
+	} `json:"response"`
+}
+
+// Make API call to Steam's GetBadges endpoint
+func (apicfg *ApiConfig) GetBadges(steamID string) (Badges, error) {
+	if badges, found := apicfg.BadgesCache.ReadCache(steamID); found {
+		return badges, nil
+	}
+
+	body := BadgesResponse{}
+	err := apicfg.getSteamJSON(steamPlayerURL, "GetBadges", "v1/", url.Values{"steamid": {steamID}}, &body)
+	if err != nil {
+		return Badges{}, err
+	}
+
+	badges := Badges{
+		PlayerXP:          body.Response.PlayerXP,
+		PlayerLevel:       body.Response.PlayerLevel,
+		XPNeededToLevelUp: body.Response.XPNeededToLevelUp,
+		Badges:            []Badge{},
+	}
+	for _, badge := range body.Response.Badges {
+		badges.Badges = append(badges.Badges, Badge(badge))
+	}
+
+	apicfg.BadgesCache.UpdateCache(steamID, badges)
+
+	return badges, nil
+}
+
+// Helper function for Steam Web API calls that decode a JSON body, the API key is added to the query
+func (apicfg *ApiConfig) getSteamJSON(service, method, version string, query url.Values, target any) error {
+	baseURL, err := url.Parse(steamMainAPIURL)
+	if err != nil {
+		return err
+	}
+
+	fullURL := baseURL.JoinPath(service, method, version)
+
+	query.Set("key", apicfg.SteamApiKey)
+	fullURL.RawQuery = query.Encode()
+
+	resp, err := http.Get(fullURL.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/json") {
+		testBody, _ := io.ReadAll(resp.Body)
+		fmt.Printf("Unexpected response from Steam API: %s\n", testBody)
+		return errors.New("steam API returned non-JSON response")
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
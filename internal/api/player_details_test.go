@@ -0,0 +1,53 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePlayerIncludes(t *testing.T) {
+	includes, err := ParsePlayerIncludes("bans, level")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !includes.Bans || !includes.Level || includes.Badges {
+		t.Errorf("expected bans and level, got %+v", includes)
+	}
+
+	if _, err := ParsePlayerIncludes("bans,friends"); err == nil {
+		t.Error("expected an unknown include to be rejected")
+	}
+
+	none, err := ParsePlayerIncludes("")
+	if err != nil || none.Any() {
+		t.Errorf("expected nothing to be included, got %+v and %v", none, err)
+	}
+}
+
+func TestAddPlayerDetailsFromCache(t *testing.T) {
+	apicfg := &ApiConfig{
+		BansCache:   Cache[PlayerBans]{Cache: map[string]CachedData[PlayerBans]{}, RenewTime: time.Hour},
+		LevelCache:  Cache[int]{Cache: map[string]CachedData[int]{}, RenewTime: time.Hour},
+		BadgesCache: Cache[Badges]{Cache: map[string]CachedData[Badges]{}, RenewTime: time.Hour},
+	}
+	apicfg.BansCache.UpdateCache("1", PlayerBans{SteamID: "1", VACBanned: true, NumberOfVACBans: 1})
+	apicfg.BansCache.UpdateCache("2", PlayerBans{SteamID: "2"})
+	apicfg.LevelCache.UpdateCache("1", 42)
+	apicfg.LevelCache.UpdateCache("2", 7)
+
+	players := []Player{{SteamID: "1"}, {SteamID: "2"}}
+	err := apicfg.AddPlayerDetails(players, PlayerIncludes{Bans: true, Level: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if players[0].Bans == nil || !players[0].Bans.VACBanned {
+		t.Errorf("expected the VAC ban to be merged in, got %+v", players[0].Bans)
+	}
+	if players[0].SteamLevel == nil || *players[0].SteamLevel != 42 || *players[1].SteamLevel != 7 {
+		t.Errorf("expected Steam levels 42 and 7, got %v and %v", players[0].SteamLevel, players[1].SteamLevel)
+	}
+	if players[0].Badges != nil {
+		t.Error("expected badges to be left out when not included")
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// Steam's recent playtime covers the two weeks before it's read
+const recentPlaytimePeriod = 14 * 24 * time.Hour
+
+// One reading of a game's total playtime, samples are only stored when the total changes so the total at
+// any time is the one from the latest sample before it. Playtime2Weeks is what Steam reported as played in
+// the two weeks before the sample
+type PlaytimeSample struct {
+	SteamID         string
+	AppID           int
+	Name            string
+	SampledAt       time.Time
+	PlaytimeForever int
+	Playtime2Weeks  int
+}
+
+type GamePlaytime struct {
+	AppID   int     `json:"appID"`
+	Name    string  `json:"name"`
+	Minutes int     `json:"minutes"`
+	Hours   float64 `json:"hours"`
+}
+
+type PlaytimePeriod struct {
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	TotalMinutes int            `json:"totalMinutes"`
+	TotalHours   float64        `json:"totalHours"`
+	Games        []GamePlaytime `json:"games"`
+}
+
+// Tracked is false for players without any samples of the game, their playtime is unknown rather than zero
+type PlayerPlaytime struct {
+	SteamID string  `json:"steamID"`
+	Tracked bool    `json:"tracked"`
+	Minutes int     `json:"minutes"`
+	Hours   float64 `json:"hours"`
+}
+
+// Minutes played per game between from and to, sorted by the most played first. Before the first sample of a
+// game only the two weeks of recent playtime reported with it are known, anything played earlier isn't counted
+func PlaytimeInWindow(samples []PlaytimeSample, from, to time.Time) []GamePlaytime {
+	byApp := map[int][]PlaytimeSample{}
+	for _, sample := range samples {
+		byApp[sample.AppID] = append(byApp[sample.AppID], sample)
+	}
+
+	games := []GamePlaytime{}
+	for appID, appSamples := range byApp {
+		minutes, name := playtimeGained(appSamples, from, to)
+		if minutes > 0 {
+			games = append(games, GamePlaytime{
+				AppID:   appID,
+				Name:    name,
+				Minutes: minutes,
+				Hours:   minutesToHours(minutes),
+			})
+		}
+	}
+
+	slices.SortFunc(games, func(a, b GamePlaytime) int {
+		if a.Minutes != b.Minutes {
+			return cmp.Compare(b.Minutes, a.Minutes)
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return games
+}
+
+// Splits the samples into one period per window, windows are returned by WeekWindows and MonthWindows
+func PlaytimePeriods(samples []PlaytimeSample, windows [][2]time.Time) []PlaytimePeriod {
+	periods := []PlaytimePeriod{}
+	for _, window := range windows {
+		games := PlaytimeInWindow(samples, window[0], window[1])
+		total := 0
+		for _, game := range games {
+			total += game.Minutes
+		}
+		periods = append(periods, PlaytimePeriod{
+			Start:        window[0],
+			End:          window[1],
+			TotalMinutes: total,
+			TotalHours:   minutesToHours(total),
+			Games:        games,
+		})
+	}
+	return periods
+}
+
+// Ranks players by how much they played between from and to, the samples should all be of the same game.
+// Every Steam ID in steamIDs is included even when it has no samples
+func RankPlayersByPlaytime(samples []PlaytimeSample, steamIDs []string, from, to time.Time) []PlayerPlaytime {
+	bySteamID := map[string][]PlaytimeSample{}
+	for _, sample := range samples {
+		bySteamID[sample.SteamID] = append(bySteamID[sample.SteamID], sample)
+	}
+
+	players := []PlayerPlaytime{}
+	for _, steamID := range steamIDs {
+		minutes, _ := playtimeGained(bySteamID[steamID], from, to)
+		players = append(players, PlayerPlaytime{
+			SteamID: steamID,
+			Tracked: len(bySteamID[steamID]) > 0,
+			Minutes: minutes,
+			Hours:   minutesToHours(minutes),
+		})
+	}
+
+	slices.SortStableFunc(players, func(a, b PlayerPlaytime) int {
+		return cmp.Compare(b.Minutes, a.Minutes)
+	})
+
+	return players
+}
+
+// The last count weeks up to and including the one now is in, weeks start on Monday in UTC and the oldest is first
+func WeekWindows(now time.Time, count int) [][2]time.Time {
+	now = now.UTC()
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	start := time.Date(now.Year(), now.Month(), now.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+
+	windows := make([][2]time.Time, count)
+	for i := count - 1; i >= 0; i-- {
+		windows[i] = [2]time.Time{start, start.AddDate(0, 0, 7)}
+		start = start.AddDate(0, 0, -7)
+	}
+	return windows
+}
+
+// The last count calendar months up to and including the one now is in, in UTC and the oldest first
+func MonthWindows(now time.Time, count int) [][2]time.Time {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	windows := make([][2]time.Time, count)
+	for i := count - 1; i >= 0; i-- {
+		windows[i] = [2]time.Time{start, start.AddDate(0, 1, 0)}
+		start = start.AddDate(0, -1, 0)
+	}
+	return windows
+}
+
+// Minutes one game's total went up between from and to, the samples have to be of a single game and player.
+// The latest sample at or before from is the starting point, without one the first sample in the window is
+// and the recent playtime of the very first sample is added for the part of the window it covers
+func playtimeGained(samples []PlaytimeSample, from, to time.Time) (int, string) {
+	if len(samples) == 0 {
+		return 0, ""
+	}
+
+	slices.SortFunc(samples, func(a, b PlaytimeSample) int {
+		return a.SampledAt.Compare(b.SampledAt)
+	})
+
+	gained := 0
+	if samples[0].SampledAt.After(from) {
+		gained += playtimeBeforeFirstSample(samples[0], from, to)
+	}
+
+	start, end := -1, -1
+	name := samples[0].Name
+	for _, sample := range samples {
+		if sample.SampledAt.After(to) {
+			break
+		}
+		if !sample.SampledAt.After(from) || start < 0 {
+			start = sample.PlaytimeForever
+		}
+		end = sample.PlaytimeForever
+		name = sample.Name
+	}
+
+	if start >= 0 && end >= start {
+		gained += end - start
+	}
+	return gained, name
+}
+
+// Spreads the recent playtime reported with a game's first sample evenly over the two weeks before it and
+// returns the part that falls between from and to
+func playtimeBeforeFirstSample(first PlaytimeSample, from, to time.Time) int {
+	minutes := min(first.Playtime2Weeks, first.PlaytimeForever)
+	if minutes <= 0 {
+		return 0
+	}
+
+	overlapStart := first.SampledAt.Add(-recentPlaytimePeriod)
+	if from.After(overlapStart) {
+		overlapStart = from
+	}
+	overlapEnd := first.SampledAt
+	if to.Before(overlapEnd) {
+		overlapEnd = to
+	}
+	if !overlapEnd.After(overlapStart) {
+		return 0
+	}
+
+	return int(math.Round(float64(minutes) * float64(overlapEnd.Sub(overlapStart)) / float64(recentPlaytimePeriod)))
+}
+
+func minutesToHours(minutes int) float64 {
+	return math.Round(float64(minutes)/60*10) / 10
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlaytimeInWindow(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2025, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	samples := []PlaytimeSample{
+		{AppID: 548430, Name: "Deep Rock Galactic", SampledAt: day(1), PlaytimeForever: 1000},
+		{AppID: 548430, Name: "Deep Rock Galactic", SampledAt: day(5), PlaytimeForever: 1200},
+		{AppID: 548430, Name: "Deep Rock Galactic", SampledAt: day(12), PlaytimeForever: 1500},
+		// First seen inside the window, only what was played after this sample counts
+		{AppID: 620, Name: "Portal 2", SampledAt: day(8), PlaytimeForever: 300},
+		{AppID: 620, Name: "Portal 2", SampledAt: day(9), PlaytimeForever: 330},
+		// Played after the window ends
+		{AppID: 570, Name: "Dota 2", SampledAt: day(3), PlaytimeForever: 50},
+		{AppID: 570, Name: "Dota 2", SampledAt: day(20), PlaytimeForever: 500},
+	}
+
+	games := PlaytimeInWindow(samples, day(4), day(14))
+
+	if len(games) != 2 {
+		t.Fatalf("expected 2 games, got %v", games)
+	}
+	if games[0].AppID != 548430 || games[0].Minutes != 500 || games[0].Hours != 8.3 {
+		t.Errorf("expected Deep Rock Galactic with 500 minutes first, got %+v", games[0])
+	}
+	if games[1].AppID != 620 || games[1].Minutes != 30 {
+		t.Errorf("expected Portal 2 with 30 minutes second, got %+v", games[1])
+	}
+}
+
+func TestPlaytimeBeforeFirstSample(t *testing.T) {
+	firstSample := time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)
+	samples := []PlaytimeSample{
+		{AppID: 548430, Name: "Deep Rock Galactic", SampledAt: firstSample, PlaytimeForever: 5000, Playtime2Weeks: 840},
+		{AppID: 548430, Name: "Deep Rock Galactic", SampledAt: firstSample.AddDate(0, 0, 3), PlaytimeForever: 5100},
+	}
+
+	// The week before the first sample gets half of its two weeks of recent playtime
+	before := PlaytimeInWindow(samples, firstSample.AddDate(0, 0, -7), firstSample)
+	if len(before) != 1 || before[0].Minutes != 420 {
+		t.Errorf("expected 420 minutes in the week before the first sample, got %v", before)
+	}
+
+	// A window around the first sample gets its share of the recent playtime plus what was played after it
+	around := PlaytimeInWindow(samples, firstSample.AddDate(0, 0, -1), firstSample.AddDate(0, 0, 7))
+	if len(around) != 1 || around[0].Minutes != 160 {
+		t.Errorf("expected 160 minutes around the first sample, got %v", around)
+	}
+
+	// Nothing is known from before the two weeks
+	if earlier := PlaytimeInWindow(samples, firstSample.AddDate(0, 0, -28), firstSample.AddDate(0, 0, -14)); len(earlier) != 0 {
+		t.Errorf("expected nothing before the two weeks, got %v", earlier)
+	}
+}
+
+func TestRankPlayersByPlaytime(t *testing.T) {
+	from := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	samples := []PlaytimeSample{
+		{SteamID: "1", AppID: 548430, SampledAt: from.AddDate(0, 0, -2), PlaytimeForever: 100},
+		{SteamID: "1", AppID: 548430, SampledAt: from.AddDate(0, 0, 10), PlaytimeForever: 160},
+		{SteamID: "2", AppID: 548430, SampledAt: from.AddDate(0, 0, -5), PlaytimeForever: 0},
+		{SteamID: "2", AppID: 548430, SampledAt: from.AddDate(0, 0, 20), PlaytimeForever: 240},
+	}
+
+	players := RankPlayersByPlaytime(samples, []string{"1", "2", "3"}, from, to)
+
+	if len(players) != 3 {
+		t.Fatalf("expected every Steam ID to be ranked, got %v", players)
+	}
+	if players[0].SteamID != "2" || players[0].Minutes != 240 {
+		t.Errorf("expected Steam ID 2 first with 240 minutes, got %+v", players[0])
+	}
+	if players[1].SteamID != "1" || players[1].Minutes != 60 {
+		t.Errorf("expected Steam ID 1 second with 60 minutes, got %+v", players[1])
+	}
+	if players[2].SteamID != "3" || players[2].Minutes != 0 || players[2].Tracked {
+		t.Errorf("expected Steam ID 3 last without playtime, got %+v", players[2])
+	}
+}
+
+func TestWeekAndMonthWindows(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, time.March, 12, 15, 30, 0, 0, time.UTC)
+
+	weeks := WeekWindows(now, 2)
+	if !weeks[1][0].Equal(time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the current week to start on Monday March 10, got %s", weeks[1][0])
+	}
+	if !weeks[0][1].Equal(weeks[1][0]) {
+		t.Errorf("expected weeks to be back to back, got %v", weeks)
+	}
+
+	months := MonthWindows(now, 3)
+	if !months[0][0].Equal(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the oldest month to be January, got %s", months[0][0])
+	}
+	if !months[2][1].Equal(time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the current month to end on April 1, got %s", months[2][1])
+	}
+}
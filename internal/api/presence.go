@@ -0,0 +1,173 @@
+package api
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"strings"
+)
+
+const (
+	personaStateOffline = iota
+	personaStateOnline
+	personaStateBusy
+	personaStateAway
+	personaStateSnooze
+	personaStateLookingToTrade
+	personaStateLookingToPlay
+)
+
+var personaStateNames = map[int]string{
+	personaStateOffline:        "offline",
+	personaStateOnline:         "online",
+	personaStateBusy:           "busy",
+	personaStateAway:           "away",
+	personaStateSnooze:         "snooze",
+	personaStateLookingToTrade: "looking_to_trade",
+	personaStateLookingToPlay:  "looking_to_play",
+}
+
+// Friends that are playing something come back grouped by game, everyone else that isn't offline is listed
+// by presence. Offline friends are only counted
+type OnlineFriends struct {
+	FriendCount int             `json:"friendCount"`
+	OnlineCount int             `json:"onlineCount"`
+	InGameCount int             `json:"inGameCount"`
+	Games       []GamePresence  `json:"games"`
+	Presence    []PresenceGroup `json:"presence"`
+	Unavailable []string        `json:"unavailable,omitempty"`
+}
+
+type GamePresence struct {
+	GameID  string   `json:"gameID"`
+	Name    string   `json:"name"`
+	Friends []Player `json:"friends"`
+}
+
+type PresenceGroup struct {
+	Presence string   `json:"presence"`
+	Friends  []Player `json:"friends"`
+}
+
+// Turns Steam's personastate number into a readable name, unknown states are reported as online since Steam
+// only adds new states for players that are signed in
+func PersonaStateName(state int) string {
+	name, found := personaStateNames[state]
+	if !found {
+		return personaStateNames[personaStateOnline]
+	}
+	return name
+}
+
+// Gets the current presence of the given players. This uses its own cache with a short renew time since the
+// summaries kept in PlayerCache can be up to a day old, which is fine for names and avatars but not for who is online
+func (apicfg *ApiConfig) GetPresence(steamIDs []string) ([]Player, error) {
+	players := []Player{}
+	uncachedIDs := []string{}
+
+	for _, steamID := range steamIDs {
+		cache, found := apicfg.PresenceCache.ReadCache(steamID)
+		if found {
+			players = append(players, cache)
+		} else {
+			uncachedIDs = append(uncachedIDs, steamID)
+		}
+	}
+
+	fetched, err := apicfg.fetchPlayerSummaryBatches(uncachedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, player := range fetched {
+		log.Printf("Adding presence to cache with steamID: %s\n", player.SteamID)
+		apicfg.PresenceCache.UpdateCache(player.SteamID, player)
+		// The summary is as fresh as it gets, so the long lived cache might as well have it too
+		apicfg.PlayerCache.UpdateCache(player.SteamID, player)
+	}
+	players = append(players, fetched...)
+
+	return players, nil
+}
+
+// Helper function that groups players by the game they're in and then by presence. Games with the most friends in
+// them come first, presence groups follow the order of Steam's personastate values and friends are sorted by name
+func buildOnlineFriends(friendIDs []string, players []Player) OnlineFriends {
+	online := OnlineFriends{
+		FriendCount: len(friendIDs),
+		Games:       []GamePresence{},
+		Presence:    []PresenceGroup{},
+	}
+
+	returned := map[string]bool{}
+	byGame := map[string]*GamePresence{}
+	byState := map[int][]Player{}
+
+	for _, player := range players {
+		returned[player.SteamID] = true
+
+		if player.PersonaState == personaStateOffline && player.GameID == "" {
+			continue
+		}
+		online.OnlineCount++
+
+		if player.GameID == "" {
+			byState[player.PersonaState] = append(byState[player.PersonaState], player)
+			continue
+		}
+
+		online.InGameCount++
+		game, found := byGame[player.GameID]
+		if !found {
+			game = &GamePresence{
+				GameID: player.GameID,
+				Name:   player.GameExtraInfo,
+			}
+			byGame[player.GameID] = game
+		}
+		game.Friends = append(game.Friends, player)
+	}
+
+	for _, friendID := range friendIDs {
+		if !returned[friendID] {
+			online.Unavailable = append(online.Unavailable, friendID)
+		}
+	}
+
+	for _, game := range byGame {
+		sortPlayersByName(game.Friends)
+		online.Games = append(online.Games, *game)
+	}
+	slices.SortFunc(online.Games, func(a, b GamePresence) int {
+		if len(a.Friends) != len(b.Friends) {
+			return cmp.Compare(len(b.Friends), len(a.Friends))
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	states := []int{}
+	for state := range byState {
+		states = append(states, state)
+	}
+	slices.Sort(states)
+
+	for _, state := range states {
+		friends := byState[state]
+		sortPlayersByName(friends)
+		online.Presence = append(online.Presence, PresenceGroup{
+			Presence: PersonaStateName(state),
+			Friends:  friends,
+		})
+	}
+
+	return online
+}
+
+func sortPlayersByName(players []Player) {
+	slices.SortFunc(players, func(a, b Player) int {
+		if c := cmp.Compare(strings.ToLower(a.PersonaName), strings.ToLower(b.PersonaName)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.SteamID, b.SteamID)
+	})
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestBuildOnlineFriends(t *testing.T) {
+	friendIDs := []string{"1", "2", "3", "4", "5", "6"}
+	players := []Player{
+		{SteamID: "1", PersonaName: "zed", PersonaState: personaStateOnline, GameID: "548430", GameExtraInfo: "Deep Rock Galactic"},
+		{SteamID: "2", PersonaName: "Amy", PersonaState: personaStateBusy, GameID: "548430", GameExtraInfo: "Deep Rock Galactic"},
+		{SteamID: "3", PersonaName: "bob", PersonaState: personaStateOnline, GameID: "620", GameExtraInfo: "Portal 2"},
+		{SteamID: "4", PersonaName: "cat", PersonaState: personaStateAway},
+		{SteamID: "5", PersonaName: "dan", PersonaState: personaStateOffline},
+	}
+
+	online := buildOnlineFriends(friendIDs, players)
+
+	if online.FriendCount != 6 || online.OnlineCount != 4 || online.InGameCount != 3 {
+		t.Errorf("expected 6 friends, 4 online and 3 in game, got %d, %d and %d", online.FriendCount, online.OnlineCount, online.InGameCount)
+	}
+	if len(online.Games) != 2 {
+		t.Fatalf("expected 2 games, got %v", online.Games)
+	}
+
+	top := online.Games[0]
+	if top.GameID != "548430" || len(top.Friends) != 2 {
+		t.Fatalf("expected Deep Rock Galactic with 2 friends first, got %+v", top)
+	}
+	if top.Friends[0].SteamID != "2" {
+		t.Errorf("expected friends sorted by name ignoring case, got %v", top.Friends)
+	}
+
+	if len(online.Presence) != 1 || online.Presence[0].Presence != "away" || online.Presence[0].Friends[0].SteamID != "4" {
+		t.Errorf("expected only Steam ID 4 listed as away, got %v", online.Presence)
+	}
+	if len(online.Unavailable) != 1 || online.Unavailable[0] != "6" {
+		t.Errorf("expected Steam ID 6 to be unavailable, got %v", online.Unavailable)
+	}
+}
+
+func TestPersonaStateName(t *testing.T) {
+	if got := PersonaStateName(personaStateLookingToPlay); got != "looking_to_play" {
+		t.Errorf("expected looking_to_play, got %s", got)
+	}
+	if got := PersonaStateName(99); got != "online" {
+		t.Errorf("expected unknown states to be online, got %s", got)
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+type RecommendingFriend struct {
+	SteamID         string  `json:"steamID"`
+	Similarity      float64 `json:"similarity"`
+	PlaytimeForever int     `json:"playtime_forever"`
+}
+
+type Recommendation struct {
+	AppID         int                  `json:"appID"`
+	Name          string               `json:"name"`
+	ImgIconURL    string               `json:"img_icon_url"`
+	Score         float64              `json:"score"`
+	TotalPlaytime int                  `json:"totalPlaytime"`
+	Friends       []RecommendingFriend `json:"friends"`
+}
+
+// Adds up the games friends own that the user doesn't, comparisons need to be made with listGames so
+// FriendOnlyGames is filled in. Each friend's vote for a game is their similarity to the user (their score
+// relative to the most similar friend) times the log of the hours they've played it, so friends with the same
+// taste count more and a game someone sank thousands of hours into doesn't drown out everything else.
+// Games friends never played aren't recommended
+func RecommendGames(comparisons []ComparedMatchedGames, limit int) []Recommendation {
+	maxScore := 0.0
+	for _, comparison := range comparisons {
+		maxScore = max(maxScore, comparison.Score)
+	}
+
+	byApp := map[int]*Recommendation{}
+	for _, comparison := range comparisons {
+		if comparison.FriendID == "" || maxScore <= 0 {
+			continue
+		}
+		similarity := comparison.Score / maxScore
+
+		for _, game := range comparison.FriendOnlyGames {
+			weight := similarity * math.Log1p(float64(game.PlaytimeForever)/60)
+			if weight <= 0 {
+				continue
+			}
+
+			recommendation, found := byApp[game.AppID]
+			if !found {
+				recommendation = &Recommendation{
+					AppID:      game.AppID,
+					Name:       game.Name,
+					ImgIconURL: game.ImgIconURL,
+				}
+				byApp[game.AppID] = recommendation
+			}
+			recommendation.Score += weight
+			recommendation.TotalPlaytime += game.PlaytimeForever
+			recommendation.Friends = append(recommendation.Friends, RecommendingFriend{
+				SteamID:         comparison.FriendID,
+				Similarity:      math.Round(similarity*100) / 100,
+				PlaytimeForever: game.PlaytimeForever,
+			})
+		}
+	}
+
+	recommendations := []Recommendation{}
+	for _, recommendation := range byApp {
+		recommendation.Score = math.Round(recommendation.Score*100) / 100
+		slices.SortFunc(recommendation.Friends, func(a, b RecommendingFriend) int {
+			return cmp.Compare(b.PlaytimeForever, a.PlaytimeForever)
+		})
+		recommendations = append(recommendations, *recommendation)
+	}
+
+	slices.SortFunc(recommendations, func(a, b Recommendation) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestRecommendGames(t *testing.T) {
+	comparisons := []ComparedMatchedGames{
+		{
+			FriendID: "1",
+			Score:    50,
+			FriendOnlyGames: []Game{
+				{AppID: 548430, Name: "Deep Rock Galactic", PlaytimeForever: 6000},
+				{AppID: 620, Name: "Portal 2", PlaytimeForever: 600},
+				// Owned but never played isn't a recommendation
+				{AppID: 440, Name: "Team Fortress 2", PlaytimeForever: 0},
+			},
+		},
+		{
+			FriendID: "2",
+			Score:    10,
+			FriendOnlyGames: []Game{
+				{AppID: 620, Name: "Portal 2", PlaytimeForever: 300},
+			},
+		},
+		// A friend whose games couldn't be read
+		{},
+	}
+
+	recommendations := RecommendGames(comparisons, 10)
+
+	if len(recommendations) != 2 {
+		t.Fatalf("expected 2 recommendations, got %v", recommendations)
+	}
+	if recommendations[0].AppID != 548430 {
+		t.Errorf("expected Deep Rock Galactic first, got %+v", recommendations[0])
+	}
+
+	portal := recommendations[1]
+	if portal.AppID != 620 || len(portal.Friends) != 2 || portal.TotalPlaytime != 900 {
+		t.Errorf("expected Portal 2 backed by both friends with 900 minutes, got %+v", portal)
+	}
+	if portal.Friends[0].SteamID != "1" || portal.Friends[0].Similarity != 1 || portal.Friends[1].Similarity != 0.2 {
+		t.Errorf("expected friend similarity relative to the most similar friend, got %+v", portal.Friends)
+	}
+
+	if limited := RecommendGames(comparisons, 1); len(limited) != 1 {
+		t.Errorf("expected the limit to be applied, got %d recommendations", len(limited))
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"cmp"
+	"log"
+	"slices"
+)
+
+type SquadMemberCoverage struct {
+	SteamID        string  `json:"steamID"`
+	PersonaName    string  `json:"personaName"`
+	Avatar         string  `json:"avatar"`
+	LibraryVisible bool    `json:"libraryVisible"`
+	GameCount      int     `json:"gameCount"`
+	OwnedCount     int     `json:"ownedCount"`
+	Coverage       float64 `json:"coverage"`
+}
+
+type SquadGames struct {
+	CommonGames []Game                `json:"commonGames"`
+	TotalGames  int                   `json:"totalGames"`
+	Members     []SquadMemberCoverage `json:"members"`
+}
+
+// Get the games every member of a squad owns and how much of the squad's combined library each member
+// covers. Members whose library can't be read (private profiles mostly) are reported but left out of the
+// comparison, otherwise one private profile would mean there are never any common games
+func (apicfg *ApiConfig) CompareSquadGames(steamIDs []string) (SquadGames, error) {
+	summaries, err := apicfg.GetPlayerSummaries(steamIDs)
+	if err != nil {
+		return SquadGames{}, err
+	}
+
+	players := map[string]Player{}
+	for _, player := range summaries.Players {
+		players[player.SteamID] = player
+	}
+
+	libraries := []OwnedGames{}
+	for _, steamID := range steamIDs {
+		ownedGames, err := apicfg.GetOwnedGames(steamID)
+		if err != nil {
+			log.Printf("Error getting games for squad member %s: %v", steamID, err)
+			ownedGames = OwnedGames{SteamID: steamID}
+		}
+		libraries = append(libraries, ownedGames)
+	}
+
+	result := compareSquadLibraries(libraries)
+	for i := range result.Members {
+		player := players[result.Members[i].SteamID]
+		result.Members[i].PersonaName = player.PersonaName
+		result.Members[i].Avatar = player.Avatar
+	}
+
+	return result, nil
+}
+
+// Helper function that does the actual comparison, libraries are in the same order as the squad's members
+func compareSquadLibraries(libraries []OwnedGames) SquadGames {
+	owners := map[int]int{}
+	games := map[int]Game{}
+	visibleCount := 0
+
+	for _, library := range libraries {
+		if len(library.Games) == 0 {
+			continue
+		}
+		visibleCount++
+
+		seen := map[int]bool{}
+		for _, game := range library.Games {
+			if seen[game.AppID] {
+				continue
+			}
+			seen[game.AppID] = true
+			owners[game.AppID]++
+			games[game.AppID] = game
+		}
+	}
+
+	result := SquadGames{
+		CommonGames: []Game{},
+		TotalGames:  len(games),
+		Members:     []SquadMemberCoverage{},
+	}
+
+	if visibleCount > 0 {
+		for appID, count := range owners {
+			if count == visibleCount {
+				result.CommonGames = append(result.CommonGames, games[appID])
+			}
+		}
+	}
+	slices.SortFunc(result.CommonGames, func(i, j Game) int {
+		return cmp.Or(cmp.Compare(i.Name, j.Name), cmp.Compare(i.AppID, j.AppID))
+	})
+
+	for _, library := range libraries {
+		member := SquadMemberCoverage{
+			SteamID:        library.SteamID,
+			LibraryVisible: len(library.Games) > 0,
+			GameCount:      library.GameCount,
+		}
+
+		seen := map[int]bool{}
+		for _, game := range library.Games {
+			seen[game.AppID] = true
+		}
+		member.OwnedCount = len(seen)
+
+		if result.TotalGames > 0 {
+			member.Coverage = float64(member.OwnedCount) / float64(result.TotalGames)
+		}
+		result.Members = append(result.Members, member)
+	}
+
+	return result
+}
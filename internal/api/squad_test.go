@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestCompareSquadLibraries(t *testing.T) {
+	portal := Game{AppID: 400, Name: "Portal"}
+	dota := Game{AppID: 570, Name: "Dota 2"}
+	tf2 := Game{AppID: 440, Name: "Team Fortress 2"}
+
+	libraries := []OwnedGames{
+		{SteamID: "a", GameCount: 3, Games: []Game{portal, dota, tf2}},
+		{SteamID: "b", GameCount: 2, Games: []Game{dota, portal}},
+		{SteamID: "c", GameCount: 0},
+	}
+
+	result := compareSquadLibraries(libraries)
+
+	if result.TotalGames != 3 {
+		t.Errorf("TotalGames = %d, want 3", result.TotalGames)
+	}
+
+	if len(result.CommonGames) != 2 || result.CommonGames[0] != dota || result.CommonGames[1] != portal {
+		t.Errorf("CommonGames = %v, want Dota 2 and Portal sorted by name", result.CommonGames)
+	}
+
+	if len(result.Members) != 3 {
+		t.Fatalf("expected a coverage entry for every member, got %d", len(result.Members))
+	}
+	if result.Members[0].Coverage != 1 || result.Members[0].OwnedCount != 3 {
+		t.Errorf("member a = %+v, want full coverage", result.Members[0])
+	}
+	if result.Members[1].OwnedCount != 2 {
+		t.Errorf("member b owned = %d, want 2", result.Members[1].OwnedCount)
+	}
+	if result.Members[2].LibraryVisible || result.Members[2].Coverage != 0 {
+		t.Errorf("member c = %+v, private library should be reported but not compared", result.Members[2])
+	}
+}
+
+func TestCompareSquadLibrariesAllPrivate(t *testing.T) {
+	result := compareSquadLibraries([]OwnedGames{{SteamID: "a"}, {SteamID: "b"}})
+
+	if len(result.CommonGames) != 0 || result.TotalGames != 0 {
+		t.Errorf("expected no games, got %+v", result)
+	}
+}
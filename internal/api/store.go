@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultStoreAPIURL = "https://store.steampowered.com/api/"
+
+// The store API allows roughly 200 requests every 5 minutes, so uncached app details are fetched this far apart.
+// The spacing is shared by every request and job through ApiConfig, not kept per call
+const StoreRequestSpacing = 1500 * time.Millisecond
+
+// Stored app details older than this are fetched again, prices and categories do change
+const AppDetailsMaxAge = 7 * 24 * time.Hour
+
+// Steam store category IDs, anything playable together counts as multiplayer
+var (
+	coopCategoryIDs        = []int{9, 38, 39, 48}
+	multiplayerCategoryIDs = []int{1, 9, 20, 24, 27, 36, 38, 39, 47, 48, 49}
+)
+
+type Price struct {
+	Currency        string `json:"currency"`
+	InitialCents    int    `json:"initialCents"`
+	FinalCents      int    `json:"finalCents"`
+	DiscountPercent int    `json:"discountPercent"`
+}
+
+// Store metadata for an app, Found is false for apps the store no longer lists (delisted games, old betas)
+// so they aren't looked up again every time
+type AppDetails struct {
+	AppID       int       `json:"appID"`
+	Found       bool      `json:"found"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	IsFree      bool      `json:"isFree"`
+	Genres      []string  `json:"genres"`
+	Categories  []string  `json:"categories"`
+	Multiplayer bool      `json:"multiplayer"`
+	Coop        bool      `json:"coop"`
+	Price       *Price    `json:"price"`
+	FetchedAt   time.Time `json:"fetchedAt"`
+}
+
+// AppDetailsStore persists app details so they survive restarts and are shared between replicas
+type AppDetailsStore interface {
+	LoadAppDetails(ctx context.Context, appIDs []int, fetchedAfter time.Time) ([]AppDetails, error)
+	SaveAppDetails(ctx context.Context, details AppDetails) error
+}
+
+type appDetailsResponse map[string]struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Type       string `json:"type"`
+		Name       string `json:"name"`
+		IsFree     bool   `json:"is_free"`
+		Categories []struct {
+			ID          int    `json:"id"`
+			Description string `json:"description"`
+		} `json:"categories"`
+		Genres []struct {
+			Description string `json:"description"`
+		} `json:"genres"`
+		PriceOverview *struct {
+			Currency        string `json:"currency"`
+			Initial         int    `json:"initial"`
+			Final           int    `json:"final"`
+			DiscountPercent int    `json:"discount_percent"`
+		} `json:"price_overview"`
+	} `json:"data"`
+}
+
+// Gets store metadata for every app ID, looking in the cache first, then the store, and only then asking Steam.
+// At most fetchBudget apps are fetched from Steam per call since the store API is slow and rate limited, apps
+// that didn't fit are missing from the result and show up once the fetch_app_details job gets to them
+func (apicfg *ApiConfig) GetAppDetails(ctx context.Context, appIDs []int, fetchBudget int) (map[int]AppDetails, error) {
+	result := map[int]AppDetails{}
+	missing := []int{}
+	seen := map[int]bool{}
+	for _, appID := range appIDs {
+		if seen[appID] {
+			continue
+		}
+		seen[appID] = true
+
+		if details, found := apicfg.AppDetailsCache.ReadCache(strconv.Itoa(appID)); found {
+			result[appID] = details
+		} else {
+			missing = append(missing, appID)
+		}
+	}
+
+	if len(missing) > 0 && apicfg.AppDetailsStore != nil {
+		stored, err := apicfg.AppDetailsStore.LoadAppDetails(ctx, missing, time.Now().UTC().Add(-AppDetailsMaxAge))
+		if err != nil {
+			return nil, err
+		}
+		for _, details := range stored {
+			apicfg.AppDetailsCache.UpdateCache(strconv.Itoa(details.AppID), details)
+			result[details.AppID] = details
+		}
+	}
+
+	fetched := 0
+	for _, appID := range missing {
+		if _, found := result[appID]; found {
+			continue
+		}
+		if fetched >= fetchBudget {
+			break
+		}
+		if err := apicfg.storeRequests.Wait(ctx, StoreRequestSpacing); err != nil {
+			return result, nil
+		}
+		fetched++
+
+		details, err := apicfg.FetchAppDetails(ctx, appID)
+		if err != nil {
+			log.Printf("Error getting app details for %d: %v", appID, err)
+			continue
+		}
+		result[appID] = details
+	}
+
+	return result, nil
+}
+
+// requestSpacer hands out request slots at least spacing apart to everyone sharing it, so concurrent
+// callers together stay inside a rate limit. The zero value is ready to use
+type requestSpacer struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Waits for the next free slot, a cancelled context gives up early but its slot stays used
+func (spacer *requestSpacer) Wait(ctx context.Context, spacing time.Duration) error {
+	spacer.mu.Lock()
+	slot := spacer.next
+	if now := time.Now(); slot.Before(now) {
+		slot = now
+	}
+	spacer.next = slot.Add(spacing)
+	spacer.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Make API call to the Steam store's appdetails endpoint, the result goes into the cache and the store
+func (apicfg *ApiConfig) FetchAppDetails(ctx context.Context, appID int) (AppDetails, error) {
+	baseURL, err := url.Parse(apicfg.StoreAPIURL)
+	if err != nil {
+		return AppDetails{}, err
+	}
+
+	fullURL := baseURL.JoinPath("appdetails")
+
+	query := url.Values{}
+	query.Set("appids", strconv.Itoa(appID))
+	query.Set("filters", "basic,categories,genres,price_overview")
+
+	fullURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL.String(), nil)
+	if err != nil {
+		return AppDetails{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return AppDetails{}, err
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(contentType, "application/json") {
+		testBody, _ := io.ReadAll(resp.Body)
+		fmt.Printf("Unexpected response from Steam store API: %s\n", testBody)
+		return AppDetails{}, errors.New("steam store API returned non-JSON response")
+	}
+
+	body := appDetailsResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return AppDetails{}, err
+	}
+
+	details := convertAppDetails(appID, body)
+
+	apicfg.AppDetailsCache.UpdateCache(strconv.Itoa(appID), details)
+	if apicfg.AppDetailsStore != nil {
+		if err := apicfg.AppDetailsStore.SaveAppDetails(ctx, details); err != nil {
+			log.Printf("Error saving app details for %d: %v", appID, err)
+		}
+	}
+
+	return details, nil
+}
+
+func convertAppDetails(appID int, body appDetailsResponse) AppDetails {
+	details := AppDetails{
+		AppID:      appID,
+		Genres:     []string{},
+		Categories: []string{},
+		FetchedAt:  time.Now().UTC(),
+	}
+
+	entry, found := body[strconv.Itoa(appID)]
+	if !found || !entry.Success {
+		return details
+	}
+
+	details.Found = true
+	details.Name = entry.Data.Name
+	details.Type = entry.Data.Type
+	details.IsFree = entry.Data.IsFree
+
+	for _, genre := range entry.Data.Genres {
+		details.Genres = append(details.Genres, genre.Description)
+	}
+	for _, category := range entry.Data.Categories {
+		details.Categories = append(details.Categories, category.Description)
+		for _, id := range multiplayerCategoryIDs {
+			details.Multiplayer = details.Multiplayer || category.ID == id
+		}
+		for _, id := range coopCategoryIDs {
+			details.Coop = details.Coop || category.ID == id
+		}
+	}
+
+	if price := entry.Data.PriceOverview; price != nil {
+		details.Price = &Price{
+			Currency:        price.Currency,
+			InitialCents:    price.Initial,
+			FinalCents:      price.Final,
+			DiscountPercent: price.DiscountPercent,
+		}
+	}
+
+	return details
+}
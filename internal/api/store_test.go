@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetAppDetailsFromStoreAPI(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Query().Get("appids") {
+		case "548430":
+			w.Write([]byte(`{"548430": {"success": true, "data": {
+				"type": "game", "name": "Deep Rock Galactic", "is_free": false,
+				"categories": [{"id": 1, "description": "Multi-player"}, {"id": 38, "description": "Online Co-op"}],
+				"genres": [{"id": "1", "description": "Action"}],
+				"price_overview": {"currency": "USD", "initial": 2999, "final": 899, "discount_percent": 70}
+			}}}`))
+		default:
+			w.Write([]byte(`{"` + req.URL.Query().Get("appids") + `": {"success": false}}`))
+		}
+	}))
+	defer server.Close()
+
+	apicfg := &ApiConfig{
+		StoreAPIURL:     server.URL + "/api/",
+		AppDetailsCache: *NewCache[AppDetails](time.Hour),
+	}
+
+	details, err := apicfg.GetAppDetails(context.Background(), []int{548430, 1, 548430}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	drg := details[548430]
+	if !drg.Found || !drg.Multiplayer || !drg.Coop || drg.Genres[0] != "Action" {
+		t.Errorf("expected Deep Rock Galactic to be a multiplayer co-op action game, got %+v", drg)
+	}
+	if drg.Price == nil || drg.Price.FinalCents != 899 {
+		t.Errorf("expected the discounted price, got %+v", drg.Price)
+	}
+	if delisted, found := details[1]; !found || delisted.Found {
+		t.Errorf("expected a delisted app to be remembered as not found, got %+v", delisted)
+	}
+
+	_, err = apicfg.GetAppDetails(context.Background(), []int{548430, 1}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected the second lookup to come from the cache, store API was called %d times", requests.Load())
+	}
+}
+
+func TestGetAppDetailsRespectsFetchBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"` + req.URL.Query().Get("appids") + `": {"success": false}}`))
+	}))
+	defer server.Close()
+
+	apicfg := &ApiConfig{
+		StoreAPIURL:     server.URL,
+		AppDetailsCache: *NewCache[AppDetails](time.Hour),
+	}
+
+	details, err := apicfg.GetAppDetails(context.Background(), []int{1, 2, 3}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(details) != 0 {
+		t.Errorf("expected nothing to be fetched without a budget, got %v", details)
+	}
+}
+
+func TestGameFilter(t *testing.T) {
+	filter, err := ParseGameFilter(url.Values{"coop": {"true"}, "genre": {"action"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !filter.Active() {
+		t.Fatal("expected the filter to be active")
+	}
+
+	coopAction := AppDetails{Found: true, Coop: true, Multiplayer: true, Genres: []string{"Action"}}
+	if !filter.Matches(coopAction) {
+		t.Error("expected a co-op action game to match")
+	}
+	if filter.Matches(AppDetails{Found: true, Multiplayer: true, Genres: []string{"Action"}}) {
+		t.Error("expected a game without co-op not to match")
+	}
+	if filter.Matches(AppDetails{Found: true, Coop: true, Genres: []string{"Puzzle"}}) {
+		t.Error("expected a game of another genre not to match")
+	}
+
+	if _, err := ParseGameFilter(url.Values{"multiplayerOnly": {"maybe"}}); err == nil {
+		t.Error("expected an invalid flag to be rejected")
+	}
+}
+
+func TestApplyFilterToComparison(t *testing.T) {
+	result := ComparedMatchedGames{
+		FriendID:         "1",
+		FriendGamesCount: 10,
+		MatchingGames:    []Game{{AppID: 548430}, {AppID: 620}},
+		FriendOnlyGames:  []Game{{AppID: 440}, {AppID: 570}},
+	}
+	filter := GameFilter{MultiplayerOnly: true}
+	known := map[int]AppDetails{
+		440: {AppID: 440, Found: true, Multiplayer: true},
+	}
+
+	result.applyFilter(map[int]bool{548430: true}, 4, filter, known)
+
+	if result.Matches != 1 || result.MatchingGames[0].AppID != 548430 {
+		t.Errorf("expected only the allowed match to be kept, got %v", result.MatchingGames)
+	}
+	if len(result.FriendOnlyGames) != 1 || result.FriendOnlyGames[0].AppID != 440 {
+		t.Errorf("expected only friend only games known to match, got %v", result.FriendOnlyGames)
+	}
+	if result.UserPercentage != 0.25 || result.Score != comparisonScore(1, 0.1) {
+		t.Errorf("expected the percentages and score to be worked out again, got %+v", result)
+	}
+}
+
+func TestRequestSpacerIsSharedByCallers(t *testing.T) {
+	spacer := requestSpacer{}
+	spacing := 20 * time.Millisecond
+
+	start := time.Now()
+	done := make(chan struct{})
+	for range 3 {
+		go func() {
+			spacer.Wait(context.Background(), spacing)
+			done <- struct{}{}
+		}()
+	}
+	for range 3 {
+		<-done
+	}
+
+	if elapsed := time.Since(start); elapsed < 2*spacing {
+		t.Errorf("expected three concurrent callers to take at least %v, took %v", 2*spacing, elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	spacer.Wait(context.Background(), time.Hour)
+	if err := spacer.Wait(ctx, time.Hour); err == nil {
+		t.Error("expected a cancelled context to stop waiting")
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+)
+
+// How many shared genres are listed to explain a taste match
+const maxSharedGenres = 3
+
+type GenreHours struct {
+	Genre string  `json:"genre"`
+	Hours float64 `json:"hours"`
+	Share float64 `json:"share"`
+}
+
+// Hours played per store genre, a game with several genres counts fully towards each of them.
+// Only games with known store details are included, KnownGames says how many that was
+type TasteProfile struct {
+	SteamID    string       `json:"steamID"`
+	KnownGames int          `json:"knownGames"`
+	TotalHours float64      `json:"totalHours"`
+	Genres     []GenreHours `json:"genres"`
+}
+
+type SharedGenre struct {
+	Genre       string  `json:"genre"`
+	UserShare   float64 `json:"userShare"`
+	FriendShare float64 `json:"friendShare"`
+}
+
+// Builds the taste profile of a library, looking up at most fetchBudget unknown games in the store
+func (apicfg *ApiConfig) TasteProfile(ctx context.Context, ownedGames OwnedGames, fetchBudget int) (TasteProfile, error) {
+	appIDs := make([]int, 0, len(ownedGames.Games))
+	for _, game := range ownedGames.Games {
+		appIDs = append(appIDs, game.AppID)
+	}
+
+	details, err := apicfg.GetAppDetails(ctx, appIDs, fetchBudget)
+	if err != nil {
+		return TasteProfile{}, err
+	}
+
+	return buildTasteProfile(ownedGames, details), nil
+}
+
+func buildTasteProfile(ownedGames OwnedGames, details map[int]AppDetails) TasteProfile {
+	profile := TasteProfile{
+		SteamID: ownedGames.SteamID,
+		Genres:  []GenreHours{},
+	}
+
+	hoursByGenre := map[string]float64{}
+	genreTotal := 0.0
+	for _, game := range ownedGames.Games {
+		appDetails, found := details[game.AppID]
+		if !found || !appDetails.Found {
+			continue
+		}
+		profile.KnownGames++
+
+		hours := float64(game.PlaytimeForever) / 60
+		profile.TotalHours += hours
+		for _, genre := range appDetails.Genres {
+			hoursByGenre[genre] += hours
+			genreTotal += hours
+		}
+	}
+
+	for genre, hours := range hoursByGenre {
+		if hours <= 0 {
+			continue
+		}
+		profile.Genres = append(profile.Genres, GenreHours{
+			Genre: genre,
+			Hours: math.Round(hours*10) / 10,
+			Share: math.Round(hours/genreTotal*1000) / 1000,
+		})
+	}
+	slices.SortFunc(profile.Genres, func(a, b GenreHours) int {
+		if a.Hours != b.Hours {
+			return cmp.Compare(b.Hours, a.Hours)
+		}
+		return cmp.Compare(a.Genre, b.Genre)
+	})
+	profile.TotalHours = math.Round(profile.TotalHours*10) / 10
+
+	return profile
+}
+
+// Cosine similarity between the share of hours two players spend in each genre, from 0 (nothing in common)
+// to 1 (the same mix). Shared genres are the ones both spend the most of their time in
+func CompareTaste(user, friend TasteProfile) (float64, []SharedGenre) {
+	userShares := map[string]float64{}
+	userNorm := 0.0
+	for _, genre := range user.Genres {
+		userShares[genre.Genre] = genre.Share
+		userNorm += genre.Share * genre.Share
+	}
+
+	dot := 0.0
+	friendNorm := 0.0
+	shared := []SharedGenre{}
+	for _, genre := range friend.Genres {
+		friendNorm += genre.Share * genre.Share
+		userShare, found := userShares[genre.Genre]
+		if !found {
+			continue
+		}
+		dot += userShare * genre.Share
+		shared = append(shared, SharedGenre{
+			Genre:       genre.Genre,
+			UserShare:   userShare,
+			FriendShare: genre.Share,
+		})
+	}
+
+	slices.SortFunc(shared, func(a, b SharedGenre) int {
+		return cmp.Compare(min(b.UserShare, b.FriendShare), min(a.UserShare, a.FriendShare))
+	})
+	if len(shared) > maxSharedGenres {
+		shared = shared[:maxSharedGenres]
+	}
+
+	if userNorm == 0 || friendNorm == 0 {
+		return 0, shared
+	}
+	similarity := dot / (math.Sqrt(userNorm) * math.Sqrt(friendNorm))
+	return math.Round(similarity*1000) / 1000, shared
+}
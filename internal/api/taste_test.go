@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestTasteProfilesAndSimilarity(t *testing.T) {
+	details := map[int]AppDetails{
+		548430: {Found: true, Genres: []string{"Action", "Indie"}},
+		620:    {Found: true, Genres: []string{"Action", "Adventure"}},
+		105600: {Found: true, Genres: []string{"Action", "Indie"}},
+		1:      {Found: true, Genres: []string{"Strategy"}},
+	}
+
+	user := buildTasteProfile(OwnedGames{SteamID: "1", Games: []Game{
+		{AppID: 548430, PlaytimeForever: 600},
+		{AppID: 620, PlaytimeForever: 120},
+		// Unknown to the store, left out of the profile
+		{AppID: 999, PlaytimeForever: 6000},
+	}}, details)
+	if user.KnownGames != 2 || user.TotalHours != 12 {
+		t.Errorf("expected 2 known games with 12 hours, got %+v", user)
+	}
+	if user.Genres[0].Genre != "Action" || user.Genres[0].Hours != 12 {
+		t.Errorf("expected Action to be the top genre with 12 hours, got %+v", user.Genres)
+	}
+
+	// Owns different games but plays the same kind of games
+	similarFriend := buildTasteProfile(OwnedGames{SteamID: "2", Games: []Game{
+		{AppID: 105600, PlaytimeForever: 3000},
+	}}, details)
+	otherFriend := buildTasteProfile(OwnedGames{SteamID: "3", Games: []Game{
+		{AppID: 1, PlaytimeForever: 3000},
+	}}, details)
+
+	similar, shared := CompareTaste(user, similarFriend)
+	other, noShared := CompareTaste(user, otherFriend)
+
+	if similar <= 0.9 {
+		t.Errorf("expected a friend playing action indie games to be very similar, got %v", similar)
+	}
+	if other != 0 || len(noShared) != 0 {
+		t.Errorf("expected a strategy only friend to share nothing, got %v and %v", other, noShared)
+	}
+	if len(shared) != 2 || shared[0].Genre != "Action" {
+		t.Errorf("expected Action and Indie to be shared with Action first, got %v", shared)
+	}
+}
@@ -2,7 +2,9 @@ package auth
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,50 +12,76 @@ import (
 	"github.com/google/uuid"
 )
 
-func MakeJWTToken(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "steam-lens",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
+// Audience given to the short-lived token handed out after a correct password when the account
+// still needs a two-factor code, it must never be accepted as a real session
+const mfaPendingAudience = "steam-lens-mfa-pending"
 
-	signedToken, err := jwtToken.SignedString([]byte(tokenSecret))
-	if err != nil {
-		return "", err
+// TokenClaims holds what the rest of the server needs to know about a validated session token
+type TokenClaims struct {
+	UserID   uuid.UUID
+	IssuedAt time.Time
+}
+
+func (keySet *KeySet) MakeJWTToken(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return keySet.MakeJWTTokenIssuedAt(userID, time.Now().UTC(), expiresIn)
+}
+
+// Same as MakeJWTToken but with the issued at time chosen by the caller, used to make a token that
+// outlives a session revocation recorded in the same second
+func (keySet *KeySet) MakeJWTTokenIssuedAt(userID uuid.UUID, issuedAt time.Time, expiresIn time.Duration) (string, error) {
+	claims := keySet.newClaims(userID, issuedAt, expiresIn)
+	if keySet.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{keySet.Audience}
 	}
 
-	return signedToken, nil
+	return keySet.sign(claims)
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := jwt.RegisteredClaims{}
+func (keySet *KeySet) ValidateJWTClaims(tokenString string) (TokenClaims, error) {
+	options := []jwt.ParserOption{}
+	if keySet.Audience != "" {
+		options = append(options, jwt.WithAudience(keySet.Audience))
+	}
 
-	jwtToken, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
+	claims, err := keySet.parse(tokenString, options...)
 	if err != nil {
-		return uuid.Nil, err
+		return TokenClaims{}, err
 	}
 
-	if !jwtToken.Valid {
-		return uuid.Nil, err
+	if slices.Contains(claims.Audience, mfaPendingAudience) {
+		return TokenClaims{}, errors.New("token is only valid for completing a two-factor login")
 	}
 
-	userIDStr, err := jwtToken.Claims.GetSubject()
+	userID, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, err
+		return TokenClaims{}, err
+	}
+
+	issuedAt := time.Time{}
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
 	}
 
-	issuer, err := jwtToken.Claims.GetIssuer()
+	return TokenClaims{
+		UserID:   userID,
+		IssuedAt: issuedAt,
+	}, nil
+}
+
+func (keySet *KeySet) MakeMFAPendingToken(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	claims := keySet.newClaims(userID, time.Now().UTC(), expiresIn)
+	claims.Audience = jwt.ClaimStrings{mfaPendingAudience}
+
+	return keySet.sign(claims)
+}
+
+func (keySet *KeySet) ValidateMFAPendingToken(tokenString string) (uuid.UUID, error) {
+	claims, err := keySet.parse(tokenString, jwt.WithAudience(mfaPendingAudience))
 	if err != nil {
 		return uuid.Nil, err
 	}
-	if issuer != "steam-lens" {
-		return uuid.Nil, errors.New("invalid issuer")
-	}
 
-	userID, err := uuid.Parse(userIDStr)
+	userID, err := uuid.Parse(claims.Subject)
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -61,6 +89,72 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	return userID, nil
 }
 
+func (keySet *KeySet) newClaims(userID uuid.UUID, issuedAt time.Time, expiresIn time.Duration) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    keySet.Issuer,
+		IssuedAt:  jwt.NewNumericDate(issuedAt),
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+		Subject:   userID.String(),
+	}
+}
+
+func (keySet *KeySet) sign(claims jwt.RegisteredClaims) (string, error) {
+	jwtToken := jwt.NewWithClaims(keySet.signing.method, claims)
+	if keySet.signing.id != "" {
+		jwtToken.Header["kid"] = keySet.signing.id
+	}
+
+	signedToken, err := jwtToken.SignedString(keySet.signing.signingKey())
+	if err != nil {
+		return "", err
+	}
+
+	return signedToken, nil
+}
+
+// Parse a token with the key its kid header points at. The alg header has to match the algorithm of
+// that key, which stops a token being forged by signing with a public key as an HMAC secret or with "none"
+func (keySet *KeySet) parse(tokenString string, options ...jwt.ParserOption) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+
+	options = append(options,
+		jwt.WithIssuer(keySet.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods(keySet.allowedAlgorithms()),
+	)
+
+	jwtToken, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		key, found := keySet.verification[keyID]
+		if !found {
+			return nil, fmt.Errorf("unknown key ID %q", keyID)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing algorithm %q for key %q", token.Method.Alg(), keyID)
+		}
+		return key.verificationKey(), nil
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !jwtToken.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+func (keySet *KeySet) allowedAlgorithms() []string {
+	algorithms := []string{}
+	for _, key := range keySet.verification {
+		if !slices.Contains(algorithms, key.method.Alg()) {
+			algorithms = append(algorithms, key.method.Alg())
+		}
+	}
+	return algorithms
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	authParts := strings.Fields(headers.Get("Authorization"))
 	if len(authParts) != 2 || strings.ToLower(authParts[0]) != "bearer" {
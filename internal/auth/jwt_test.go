@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMFAPendingTokenIsNotASession(t *testing.T) {
+	userID := uuid.New()
+	keySet := NewHMACKeySet("testy", "steam-lens", "steam-lens")
+
+	pendingToken, err := keySet.MakeMFAPendingToken(userID, time.Minute)
+	if err != nil {
+		t.Fatalf("Error making pending token: %v", err)
+	}
+
+	if _, err := keySet.ValidateJWTClaims(pendingToken); err == nil {
+		t.Error("Pending two-factor token should not validate as a session token")
+	}
+
+	gotID, err := keySet.ValidateMFAPendingToken(pendingToken)
+	if err != nil {
+		t.Fatalf("Pending token should validate: %v", err)
+	}
+	if gotID != userID {
+		t.Errorf("Expected user %s, got %s", userID, gotID)
+	}
+
+	sessionToken, err := keySet.MakeJWTToken(userID, time.Minute)
+	if err != nil {
+		t.Fatalf("Error making session token: %v", err)
+	}
+	if _, err := keySet.ValidateMFAPendingToken(sessionToken); err == nil {
+		t.Error("Session token should not validate as a pending two-factor token")
+	}
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"os"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey is one key that tokens can be signed or verified with, privateKey is only set for the signing key
+type jwtKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+	hmacSecret []byte
+}
+
+func (key *jwtKey) signingKey() interface{} {
+	if key.hmacSecret != nil {
+		return key.hmacSecret
+	}
+	return key.privateKey
+}
+
+func (key *jwtKey) verificationKey() interface{} {
+	if key.hmacSecret != nil {
+		return key.hmacSecret
+	}
+	return key.publicKey
+}
+
+// KeySet signs new tokens with a single active key and accepts tokens signed by any of its keys.
+// During a rotation the old public key stays in the set so existing sessions keep working until they expire
+type KeySet struct {
+	Issuer   string
+	Audience string
+
+	signing      *jwtKey
+	verification map[string]*jwtKey
+}
+
+// Creates a key set that signs and verifies with a shared HS256 secret, tokens from it have no kid header
+func NewHMACKeySet(secret, issuer, audience string) *KeySet {
+	key := &jwtKey{
+		id:         "",
+		method:     jwt.SigningMethodHS256,
+		hmacSecret: []byte(secret),
+	}
+
+	return &KeySet{
+		Issuer:       issuer,
+		Audience:     audience,
+		signing:      key,
+		verification: map[string]*jwtKey{key.id: key},
+	}
+}
+
+// Load a PKCS#8 PEM private key (RSA or Ed25519) to sign with, plus any number of PKIX PEM public keys
+// that are only used for verifying, such as the key that was active before a rotation
+func LoadKeySetFromFiles(signingKeyPath string, verifyKeyPaths []string, issuer, audience string) (*KeySet, error) {
+	data, err := os.ReadFile(signingKeyPath) // #nosec G304 -- path comes from server configuration
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+
+	signing, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key %s: %w", signingKeyPath, err)
+	}
+
+	keySet := &KeySet{
+		Issuer:       issuer,
+		Audience:     audience,
+		signing:      signing,
+		verification: map[string]*jwtKey{signing.id: signing},
+	}
+
+	for _, path := range verifyKeyPaths {
+		data, err := os.ReadFile(path) // #nosec G304 -- path comes from server configuration
+		if err != nil {
+			return nil, fmt.Errorf("reading verification key: %w", err)
+		}
+
+		key, err := parsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing verification key %s: %w", path, err)
+		}
+		keySet.verification[key.id] = key
+	}
+
+	return keySet, nil
+}
+
+func parsePrivateKey(data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key can't be used for signing")
+	}
+
+	key, err := newAsymmetricKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.privateKey = signer
+
+	return key, nil
+}
+
+func parsePublicKey(data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAsymmetricKey(parsed)
+}
+
+// The kid of an asymmetric key is its RFC 7638 thumbprint, so the same key always gets the same ID
+func newAsymmetricKey(publicKey crypto.PublicKey) (*jwtKey, error) {
+	key := &jwtKey{publicKey: publicKey}
+
+	switch typed := publicKey.(type) {
+	case *rsa.PublicKey:
+		if typed.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, only RSA and Ed25519 are supported", publicKey)
+	}
+
+	key.id = thumbprintKeyID(key.jwk())
+
+	return key, nil
+}
+
+// JWK is a single public key as served from the JWKS endpoint
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (key *jwtKey) jwk() JWK {
+	switch typed := key.publicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(typed.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(typed.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(typed),
+		}
+	}
+	return JWK{}
+}
+
+// RFC 7638 thumbprint, the required members in lexicographic order hashed with SHA-256
+func thumbprintKeyID(jwk JWK) string {
+	var canonical string
+	switch jwk.KeyType {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Curve, jwk.X)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Public keys of every key in the set, HMAC secrets are never included
+func (keySet *KeySet) JWKS() JWKSet {
+	jwks := JWKSet{Keys: []JWK{}}
+
+	// signing key goes first so clients that only look at the first key still get the current one
+	ordered := []*jwtKey{keySet.signing}
+	for _, id := range slices.Sorted(maps.Keys(keySet.verification)) {
+		if id != keySet.signing.id {
+			ordered = append(ordered, keySet.verification[id])
+		}
+	}
+
+	for _, key := range ordered {
+		if key.hmacSecret != nil {
+			continue
+		}
+		jwk := key.jwk()
+		jwk.Use = "sig"
+		jwk.Algorithm = key.method.Alg()
+		jwk.KeyID = key.id
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Helper function to write a private key and its public key as PEM files, returns both paths
+func writeKeyFiles(t *testing.T, name string, privateKey crypto.Signer) (string, string) {
+	t.Helper()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Error marshalling private key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatalf("Error marshalling public key: %v", err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, name+".pem")
+	publicPath := filepath.Join(dir, name+".pub.pem")
+
+	err = os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+	if err != nil {
+		t.Fatalf("Error writing private key: %v", err)
+	}
+	err = os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600)
+	if err != nil {
+		t.Fatalf("Error writing public key: %v", err)
+	}
+
+	return privatePath, publicPath
+}
+
+func TestKeySetSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating Ed25519 key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{name: "RS256", key: rsaKey, alg: "RS256"},
+		{name: "EdDSA", key: edKey, alg: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privatePath, _ := writeKeyFiles(t, tt.name, tt.key)
+
+			keySet, err := LoadKeySetFromFiles(privatePath, nil, "steam-lens", "steam-lens")
+			if err != nil {
+				t.Fatalf("Error loading key set: %v", err)
+			}
+
+			userID := uuid.New()
+			token, err := keySet.MakeJWTToken(userID, time.Minute)
+			if err != nil {
+				t.Fatalf("Error making token: %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			if err != nil {
+				t.Fatalf("Error reading token header: %v", err)
+			}
+			if parsed.Header["alg"] != tt.alg {
+				t.Errorf("Expected alg %s, got %v", tt.alg, parsed.Header["alg"])
+			}
+			if parsed.Header["kid"] == "" || parsed.Header["kid"] == nil {
+				t.Error("Expected a kid header")
+			}
+
+			claims, err := keySet.ValidateJWTClaims(token)
+			if err != nil {
+				t.Fatalf("Error validating token: %v", err)
+			}
+			if claims.UserID != userID {
+				t.Errorf("Expected user %s, got %s", userID, claims.UserID)
+			}
+
+			jwks := keySet.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != parsed.Header["kid"] || jwks.Keys[0].Algorithm != tt.alg {
+				t.Errorf("JWKS doesn't match the signing key: %+v", jwks)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating old key: %v", err)
+	}
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating new key: %v", err)
+	}
+
+	oldPrivatePath, oldPublicPath := writeKeyFiles(t, "old", oldKey)
+	newPrivatePath, _ := writeKeyFiles(t, "new", newKey)
+
+	oldKeySet, err := LoadKeySetFromFiles(oldPrivatePath, nil, "steam-lens", "steam-lens")
+	if err != nil {
+		t.Fatalf("Error loading old key set: %v", err)
+	}
+	oldToken, err := oldKeySet.MakeJWTToken(uuid.New(), time.Minute)
+	if err != nil {
+		t.Fatalf("Error making token with old key: %v", err)
+	}
+
+	rotatedKeySet, err := LoadKeySetFromFiles(newPrivatePath, []string{oldPublicPath}, "steam-lens", "steam-lens")
+	if err != nil {
+		t.Fatalf("Error loading rotated key set: %v", err)
+	}
+	if _, err := rotatedKeySet.ValidateJWTClaims(oldToken); err != nil {
+		t.Errorf("Token from the previous key should still validate during rotation: %v", err)
+	}
+	if len(rotatedKeySet.JWKS().Keys) != 2 {
+		t.Errorf("Expected both keys in the JWKS, got %d", len(rotatedKeySet.JWKS().Keys))
+	}
+
+	newOnlyKeySet, err := LoadKeySetFromFiles(newPrivatePath, nil, "steam-lens", "steam-lens")
+	if err != nil {
+		t.Fatalf("Error loading new key set: %v", err)
+	}
+	if _, err := newOnlyKeySet.ValidateJWTClaims(oldToken); err == nil {
+		t.Error("Token from a retired key should not validate")
+	}
+}
+
+func TestKeySetRejectsUnexpectedClaims(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA key: %v", err)
+	}
+	privatePath, _ := writeKeyFiles(t, "rsa", rsaKey)
+
+	keySet, err := LoadKeySetFromFiles(privatePath, nil, "steam-lens", "steam-lens")
+	if err != nil {
+		t.Fatalf("Error loading key set: %v", err)
+	}
+	keyID := keySet.signing.id
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    "steam-lens",
+		Audience:  jwt.ClaimStrings{"steam-lens"},
+		Subject:   uuid.New().String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+
+	// HS256 signed with the RSA public key as the secret, the classic algorithm confusion attack
+	publicDER, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	if err != nil {
+		t.Fatalf("Error marshalling public key: %v", err)
+	}
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	confused.Header["kid"] = keyID
+	confusedToken, err := confused.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	if err != nil {
+		t.Fatalf("Error signing confused token: %v", err)
+	}
+	if _, err := keySet.ValidateJWTClaims(confusedToken); err == nil {
+		t.Error("HS256 token should be rejected by an RS256 key set")
+	}
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	unsigned.Header["kid"] = keyID
+	unsignedToken, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("Error making unsigned token: %v", err)
+	}
+	if _, err := keySet.ValidateJWTClaims(unsignedToken); err == nil {
+		t.Error("Unsigned token should be rejected")
+	}
+
+	otherAudience, err := NewHMACKeySet("testy", "steam-lens", "other-service").MakeJWTToken(uuid.New(), time.Minute)
+	if err != nil {
+		t.Fatalf("Error making token: %v", err)
+	}
+	if _, err := NewHMACKeySet("testy", "steam-lens", "steam-lens").ValidateJWTClaims(otherAudience); err == nil {
+		t.Error("Token for a different audience should be rejected")
+	}
+}
@@ -1,23 +1,187 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+
+	// bcrypt silently ignores anything past 72 bytes, longer passwords are rejected for every
+	// algorithm so switching algorithms never changes which passwords are accepted
+	MaxPasswordBytes = 72
+)
+
+var (
+	ErrPasswordTooLong   = errors.New("password is longer than 72 bytes")
+	ErrPasswordMismatch  = errors.New("password does not match")
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+)
+
+// Argon2Params are the argon2id cost settings, they're stored in every hash so they can be raised later
+type Argon2Params struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the second recommended option in RFC 9106
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// PasswordHasher hashes new passwords with the configured algorithm and checks passwords against
+// hashes made with any supported algorithm. Hashes are self-describing: argon2id hashes use the PHC
+// string format ($argon2id$v=19$m=...,t=...,p=...$salt$key) and bcrypt hashes keep their own $2a$ format
+type PasswordHasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+func NewPasswordHasher(algorithm string, bcryptCost int) (*PasswordHasher, error) {
+	if algorithm != PasswordAlgorithmArgon2id && algorithm != PasswordAlgorithmBcrypt {
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", algorithm)
+	}
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &PasswordHasher{
+		Algorithm:  algorithm,
+		BcryptCost: bcryptCost,
+		Argon2:     DefaultArgon2Params,
+	}, nil
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if len(password) > MaxPasswordBytes {
+		return "", ErrPasswordTooLong
+	}
+
+	if h.Algorithm == PasswordAlgorithmBcrypt {
+		encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(encryptedPassword), nil
+	}
+
+	salt := make([]byte, h.Argon2.SaltLength)
+	_, err := rand.Read(salt)
 	if err != nil {
 		return "", err
 	}
 
-	return string(encryptedPassword), nil
+	key := argon2.IDKey([]byte(password), salt, h.Argon2.Iterations, h.Argon2.Memory, h.Argon2.Parallelism, h.Argon2.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Argon2.Memory, h.Argon2.Iterations, h.Argon2.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
 }
 
-func CheckPasswordHash(hash, password string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+// Check compares a password with a hash made by any supported algorithm. When it matches, needsRehash
+// reports whether the hash was made with a different algorithm or weaker settings than are configured now
+func (h *PasswordHasher) Check(hash, password string) (needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2Hash(hash)
+		if err != nil {
+			return false, err
+		}
+
+		candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return false, ErrPasswordMismatch
+		}
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err != nil {
+			return false, ErrPasswordMismatch
+		}
+	default:
+		return false, ErrUnknownHashFormat
+	}
+
+	return h.NeedsRehash(hash), nil
+}
+
+// NeedsRehash reports whether a hash was made with something other than the current settings
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	if h.Algorithm == PasswordAlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.BcryptCost
+	}
+
+	params, salt, key, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.Argon2.Memory ||
+		params.Iterations != h.Argon2.Iterations ||
+		params.Parallelism != h.Argon2.Parallelism ||
+		uint32(len(salt)) != h.Argon2.SaltLength ||
+		uint32(len(key)) != h.Argon2.KeyLength
+}
+
+// Run a password check that always fails but takes as long as a real one with the current settings,
+// used when a login names a user that doesn't exist so usernames can't be enumerated by timing
+func (h *PasswordHasher) CheckDummy(password string) {
+	h.dummyOnce.Do(func() {
+		h.dummyHash, _ = h.Hash("steam-lens-dummy-password")
+	})
+	_, _ = h.Check(h.dummyHash, password)
+}
+
+func decodeArgon2Hash(hash string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=4", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2Params{}, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrUnknownHashFormat
+	}
+
+	params := Argon2Params{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
 	if err != nil {
-		return err
+		return Argon2Params{}, nil, nil, ErrUnknownHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrUnknownHashFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, ErrUnknownHashFormat
 	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
 
-	return nil
+	return params, salt, key, nil
 }
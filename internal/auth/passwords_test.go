@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"golang.org/x/crypto/bcrypt"
@@ -39,3 +41,76 @@ func TestTwoHashedPasswords(t *testing.T) {
 		t.Errorf("Password did not match hashedPassword2: %v", err)
 	}
 }
+
+// Small argon2 settings so the tests stay fast
+var testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasherArgon2id(t *testing.T) {
+	hasher := &PasswordHasher{Algorithm: PasswordAlgorithmArgon2id, BcryptCost: bcrypt.MinCost, Argon2: testArgon2Params}
+
+	hash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash() error: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected hash format: %s", hash)
+	}
+
+	needsRehash, err := hasher.Check(hash, "correct horse")
+	if err != nil || needsRehash {
+		t.Errorf("Check() = %v, %v, want false, nil", needsRehash, err)
+	}
+
+	_, err = hasher.Check(hash, "wrong horse")
+	if !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Check() with wrong password error = %v, want ErrPasswordMismatch", err)
+	}
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte("hunter22"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argonHasher := &PasswordHasher{Algorithm: PasswordAlgorithmArgon2id, BcryptCost: bcrypt.MinCost, Argon2: testArgon2Params}
+	needsRehash, err := argonHasher.Check(string(legacyHash), "hunter22")
+	if err != nil || !needsRehash {
+		t.Errorf("bcrypt hash checked by argon2id hasher = %v, %v, want true, nil", needsRehash, err)
+	}
+
+	strongerHasher := &PasswordHasher{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1}
+	needsRehash, err = strongerHasher.Check(string(legacyHash), "hunter22")
+	if err != nil || !needsRehash {
+		t.Errorf("low cost bcrypt hash = %v, %v, want true, nil", needsRehash, err)
+	}
+
+	argonHash, err := argonHasher.Hash("hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upgraded := &PasswordHasher{Algorithm: PasswordAlgorithmArgon2id, Argon2: testArgon2Params}
+	upgraded.Argon2.Iterations = 2
+	needsRehash, err = upgraded.Check(argonHash, "hunter22")
+	if err != nil || !needsRehash {
+		t.Errorf("argon2id hash with old params = %v, %v, want true, nil", needsRehash, err)
+	}
+}
+
+func TestPasswordHasherRejectsLongPasswords(t *testing.T) {
+	for _, algorithm := range []string{PasswordAlgorithmArgon2id, PasswordAlgorithmBcrypt} {
+		hasher := &PasswordHasher{Algorithm: algorithm, BcryptCost: bcrypt.MinCost, Argon2: testArgon2Params}
+		_, err := hasher.Hash(strings.Repeat("a", MaxPasswordBytes+1))
+		if !errors.Is(err, ErrPasswordTooLong) {
+			t.Errorf("%s: Hash() error = %v, want ErrPasswordTooLong", algorithm, err)
+		}
+	}
+}
+
+func TestPasswordHasherUnknownFormat(t *testing.T) {
+	hasher := &PasswordHasher{Algorithm: PasswordAlgorithmArgon2id, BcryptCost: bcrypt.MinCost, Argon2: testArgon2Params}
+	_, err := hasher.Check("plaintext", "plaintext")
+	if !errors.Is(err, ErrUnknownHashFormat) {
+		t.Errorf("Check() error = %v, want ErrUnknownHashFormat", err)
+	}
+}
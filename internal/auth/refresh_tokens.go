@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 )
 
@@ -16,3 +17,9 @@ func MakeRefreshToken() (string, error) {
 
 	return encodedData, nil
 }
+
+// Hash a random token before storing it, so a leaked database can't be used to reset passwords or verify emails
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret "12345678901234567890" from the RFC 6238 test vectors, truncated to 6 digits
+const rfcTestSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCodeRFCVectors(t *testing.T) {
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := GenerateTOTPCode(rfcTestSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Error generating code: %v", err)
+		}
+		if code != tt.expected {
+			t.Errorf("At %d expected code %s, got %s", tt.unix, tt.expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := MakeTOTPSecret()
+	if err != nil {
+		t.Fatalf("Error making secret: %v", err)
+	}
+
+	now := time.Now()
+	code, err := GenerateTOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("Error generating code: %v", err)
+	}
+
+	counter, valid := ValidateTOTP(secret, code, now, 0)
+	if !valid {
+		t.Error("Current code should be valid")
+	}
+	if _, valid := ValidateTOTP(secret, code, now.Add(totpPeriod), 0); !valid {
+		t.Error("Code from the previous period should still be valid")
+	}
+	if _, valid := ValidateTOTP(secret, code, now.Add(5*totpPeriod), 0); valid {
+		t.Error("Code from several periods ago should not be valid")
+	}
+	if _, valid := ValidateTOTP(secret, "12345", now, 0); valid {
+		t.Error("Code with the wrong length should not be valid")
+	}
+	if _, valid := ValidateTOTP(secret, code, now.Add(totpPeriod), counter); valid {
+		t.Error("Code that was already used should not be valid again")
+	}
+
+	next, err := GenerateTOTPCode(secret, now.Add(totpPeriod))
+	if err != nil {
+		t.Fatalf("Error generating code: %v", err)
+	}
+	if _, valid := ValidateTOTP(secret, next, now.Add(totpPeriod), counter); !valid {
+		t.Error("Code from a later period should be valid after an earlier one was used")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := MakeRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("Error making recovery codes: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("Expected 10 codes, got %d", len(codes))
+	}
+
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(codes[0])+" ") {
+		t.Error("Recovery code hash should ignore case and surrounding spaces")
+	}
+	if HashRecoveryCode(codes[0]) == HashRecoveryCode(codes[1]) {
+		t.Error("Different recovery codes should not hash the same")
+	}
+}
@@ -6,11 +6,105 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AccountToken struct {
+	TokenHash string
+	Purpose   string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type AppDetail struct {
+	AppID           int32
+	Found           bool
+	Name            string
+	Type            string
+	IsFree          bool
+	Genres          []string
+	Categories      []string
+	Multiplayer     bool
+	Coop            bool
+	PriceCurrency   sql.NullString
+	PriceInitial    sql.NullInt32
+	PriceFinal      sql.NullInt32
+	DiscountPercent sql.NullInt32
+	FetchedAt       time.Time
+}
+
+type AuditEvent struct {
+	ID        int64
+	CreatedAt time.Time
+	UserID    uuid.NullUUID
+	ActorID   uuid.NullUUID
+	EventType string
+	Ip        string
+	UserAgent string
+	Details   json.RawMessage
+}
+
+type JobLease struct {
+	Name        string
+	Owner       string
+	LeasedUntil time.Time
+}
+
+type JobRun struct {
+	ID         int64
+	JobName    string
+	Owner      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      sql.NullString
+}
+
+type LibrarySnapshot struct {
+	ID        uuid.UUID
+	SteamID   string
+	TakenAt   time.Time
+	GameCount int32
+	Source    string
+	CreatedBy uuid.NullUUID
+}
+
+type LibrarySnapshotGame struct {
+	SnapshotID      uuid.UUID
+	AppID           int32
+	Name            string
+	PlaytimeForever int32
+}
+
+type LoginAttempt struct {
+	Scope        string
+	Identifier   string
+	FailedCount  int32
+	LastFailedAt time.Time
+	LockedUntil  sql.NullTime
+}
+
+type PlaytimeSample struct {
+	SteamID         string
+	AppID           int32
+	SampledAt       time.Time
+	Name            string
+	PlaytimeForever int32
+	Playtime2weeks  int32
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+}
+
 type RefreshToken struct {
 	Token     string
 	CreatedAt time.Time
@@ -20,11 +114,34 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Squad struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+}
+
+type SquadMember struct {
+	SquadID  uuid.UUID
+	SteamID  string
+	Position int32
+}
+
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Username       string
-	HashedPassword string
-	SteamID        string
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Username          string
+	HashedPassword    string
+	SteamID           string
+	TotpSecret        sql.NullString
+	TotpEnabled       bool
+	TotpLastCounter   int64
+	Email             sql.NullString
+	EmailVerifiedAt   sql.NullTime
+	PendingEmail      sql.NullString
+	Role              string
+	DisabledAt        sql.NullTime
+	SessionsRevokedAt sql.NullTime
 }
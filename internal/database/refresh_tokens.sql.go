@@ -56,7 +56,7 @@ func (q *Queries) DeleteRefreshToken(ctx context.Context, token string) error {
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.username, users.hashed_password, users.steam_id FROM users
+SELECT users.id, users.created_at, users.updated_at, users.username, users.hashed_password, users.steam_id, users.totp_secret, users.totp_enabled, totp_last_counter, users.totp_last_counter, users.email, users.email_verified_at, pending_email, users.pending_email, users.role, users.disabled_at, users.sessions_revoked_at FROM users
 JOIN refresh_tokens ON users.id = refresh_tokens.user_id
 WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW()
 `
@@ -72,10 +72,73 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.Username,
 		&i.HashedPassword,
 		&i.SteamID,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SessionsRevokedAt,
 	)
 	return i, err
 }
 
+const listRefreshTokensForUser = `-- name: ListRefreshTokensForUser :many
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+// #nosec G101 -- false positive, lists token metadata for a data export
+func (q *Queries) ListRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, listRefreshTokensForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.Token,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeOtherUserRefreshTokens = `-- name: RevokeOtherUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND token <> $2 AND revoked_at IS NULL
+`
+
+type RevokeOtherUserRefreshTokensParams struct {
+	UserID uuid.UUID
+	Token  string
+}
+
+// #nosec G101 -- false positive, keeps the caller's own token and revokes the rest
+func (q *Queries) RevokeOtherUserRefreshTokens(ctx context.Context, arg RevokeOtherUserRefreshTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOtherUserRefreshTokens, arg.UserID, arg.Token)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(), updated_at = NOW()
@@ -87,3 +150,15 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
 	return err
 }
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+// #nosec G101 -- false positive, only revokes by user ID
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
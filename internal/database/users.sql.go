@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -45,6 +46,19 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) error {
 	return err
 }
 
+const deleteUser = `-- name: DeleteUser :execrows
+
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 
 DELETE FROM users
@@ -55,9 +69,71 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const disableTOTP = `-- name: DisableTOTP :exec
+
+UPDATE users
+SET totp_secret = NULL, totp_enabled = FALSE, updated_at = $2
+WHERE id = $1
+`
+
+type DisableTOTPParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) DisableTOTP(ctx context.Context, arg DisableTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, disableTOTP, arg.ID, arg.UpdatedAt)
+	return err
+}
+
+const enableTOTP = `-- name: EnableTOTP :exec
+
+UPDATE users
+SET totp_enabled = TRUE, updated_at = $2
+WHERE id = $1
+`
+
+type EnableTOTPParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) EnableTOTP(ctx context.Context, arg EnableTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, enableTOTP, arg.ID, arg.UpdatedAt)
+	return err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+
+SELECT id, created_at, updated_at, username, hashed_password, steam_id, totp_secret, totp_enabled, totp_last_counter, email, email_verified_at, pending_email, role, disabled_at, sessions_revoked_at FROM users WHERE email = $1 AND email_verified_at IS NOT NULL
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.HashedPassword,
+		&i.SteamID,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
 const getUserByID = `-- name: GetUserByID :one
 
-SELECT id, created_at, updated_at, username, hashed_password, steam_id FROM users WHERE id = $1
+SELECT id, created_at, updated_at, username, hashed_password, steam_id, totp_secret, totp_enabled, totp_last_counter, email, email_verified_at, pending_email, role, disabled_at, sessions_revoked_at FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -70,13 +146,50 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Username,
 		&i.HashedPassword,
 		&i.SteamID,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const getUserByIDForUpdate = `-- name: GetUserByIDForUpdate :one
+
+SELECT id, created_at, updated_at, username, hashed_password, steam_id, totp_secret, totp_enabled, totp_last_counter, email, email_verified_at, pending_email, role, disabled_at, sessions_revoked_at FROM users WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetUserByIDForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIDForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.HashedPassword,
+		&i.SteamID,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SessionsRevokedAt,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
 
-SELECT id, created_at, updated_at, username, hashed_password, steam_id FROM users WHERE username = $1
+SELECT id, created_at, updated_at, username, hashed_password, steam_id, totp_secret, totp_enabled, totp_last_counter, email, email_verified_at, pending_email, role, disabled_at, sessions_revoked_at FROM users WHERE username = $1
 `
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
@@ -89,11 +202,240 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Username,
 		&i.HashedPassword,
 		&i.SteamID,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SessionsRevokedAt,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :exec
+const listActiveSteamIDs = `-- name: ListActiveSteamIDs :many
+
+SELECT DISTINCT steam_id FROM users
+WHERE disabled_at IS NULL
+ORDER BY steam_id
+`
+
+func (q *Queries) ListActiveSteamIDs(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSteamIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var steam_id string
+		if err := rows.Scan(&steam_id); err != nil {
+			return nil, err
+		}
+		items = append(items, steam_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+
+SELECT id, created_at, updated_at, username, hashed_password, steam_id, totp_secret, totp_enabled, totp_last_counter, email, email_verified_at, pending_email, role, disabled_at, sessions_revoked_at FROM users
+ORDER BY created_at
+LIMIT $1 OFFSET $2
+`
+
+type ListUsersParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Username,
+			&i.HashedPassword,
+			&i.SteamID,
+			&i.TotpSecret,
+			&i.TotpEnabled,
+			&i.TotpLastCounter,
+			&i.Email,
+			&i.EmailVerifiedAt,
+			&i.PendingEmail,
+			&i.Role,
+			&i.DisabledAt,
+			&i.SessionsRevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+
+UPDATE users
+SET email = pending_email = NULL, email_verified_at = $2, updated_at = $2
+WHERE id = $1 AND pending_email IS NOT NULL
+`
+
+type MarkEmailVerifiedParams struct {
+	ID              uuid.UUID
+	EmailVerifiedAt sql.NullTime
+}
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markEmailVerified, arg.ID, arg.EmailVerifiedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+
+UPDATE users
+SET hashed_password = $1
+WHERE id = $2 AND hashed_password = $3
+`
+
+type RehashUserPasswordParams struct {
+	NewHash string
+	ID      uuid.UUID
+	OldHash string
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rehashUserPassword, arg.NewHash, arg.ID, arg.OldHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :execrows
+
+UPDATE users
+SET sessions_revoked_at = $2
+WHERE id = $1
+`
+
+type RevokeUserSessionsParams struct {
+	ID                uuid.UUID
+	SessionsRevokedAt sql.NullTime
+}
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, arg RevokeUserSessionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserSessions, arg.ID, arg.SessionsRevokedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPendingEmail = `-- name: SetPendingEmail :exec
+
+UPDATE users
+SET pending_email = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetPendingEmailParams struct {
+	ID           uuid.UUID
+	PendingEmail sql.NullString
+	UpdatedAt    time.Time
+}
+
+func (q *Queries) SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) error {
+	_, err := q.db.ExecContext(ctx, setPendingEmail, arg.ID, arg.PendingEmail, arg.UpdatedAt)
+	return err
+}
+
+const setTOTPSecret = `-- name: SetTOTPSecret :exec
+
+UPDATE users
+SET totp_secret = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetTOTPSecretParams struct {
+	ID         uuid.UUID
+	TotpSecret sql.NullString
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setTOTPSecret, arg.ID, arg.TotpSecret, arg.UpdatedAt)
+	return err
+}
+
+const setUserDisabled = `-- name: SetUserDisabled :execrows
+
+UPDATE users
+SET disabled_at = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetUserDisabledParams struct {
+	ID         uuid.UUID
+	DisabledAt sql.NullTime
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserDisabled, arg.ID, arg.DisabledAt, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserRoleByUsername = `-- name: SetUserRoleByUsername :execrows
+
+UPDATE users
+SET role = $2, updated_at = $3
+WHERE username = $1
+`
+
+type SetUserRoleByUsernameParams struct {
+	Username  string
+	Role      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetUserRoleByUsername(ctx context.Context, arg SetUserRoleByUsernameParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRoleByUsername, arg.Username, arg.Role, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUser = `-- name: UpdateUser :one
 
 UPDATE users
 SET
@@ -102,6 +444,7 @@ SET
     steam_id = COALESCE(NULLIF($3::text, ''), steam_id),
     updated_at = $4
 WHERE id = $5
+RETURNING id, created_at, updated_at, username, hashed_password, steam_id, totp_secret, totp_enabled, totp_last_counter, email, email_verified_at, pending_email, role, disabled_at, sessions_revoked_at
 `
 
 type UpdateUserParams struct {
@@ -112,13 +455,69 @@ type UpdateUserParams struct {
 	ID        uuid.UUID
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
-	_, err := q.db.ExecContext(ctx, updateUser,
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser,
 		arg.Column1,
 		arg.Column2,
 		arg.Column3,
 		arg.UpdatedAt,
 		arg.ID,
 	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.HashedPassword,
+		&i.SteamID,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+
+UPDATE users
+SET hashed_password = $2, updated_at = $3
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+	UpdatedAt      time.Time
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword, arg.UpdatedAt)
 	return err
 }
+
+const useTOTPCounter = `-- name: UseTOTPCounter :execrows
+
+UPDATE users
+SET totp_last_counter = $1
+WHERE id = $2 AND totp_last_counter < $1
+`
+
+type UseTOTPCounterParams struct {
+	Counter int64
+	ID      uuid.UUID
+}
+
+func (q *Queries) UseTOTPCounter(ctx context.Context, arg UseTOTPCounterParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useTOTPCounter, arg.Counter, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
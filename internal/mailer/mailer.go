@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is implemented by anything that can deliver an email, the server only ever talks to this
+// so SMTP in production and logging in development can be swapped through configuration
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer sends mail through an SMTP server, Username can be left empty for servers
+// that don't need auth such as MailHog during local development
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var smtpAuth smtp.Auth
+	if m.Username != "" {
+		smtpAuth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(net.JoinHostPort(m.Host, m.Port), smtpAuth, m.From, []string{msg.To}, m.buildMessage(msg))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("sending mail to %s: %w", msg.To, err)
+		}
+		return nil
+	}
+}
+
+func (m *SMTPMailer) buildMessage(msg Message) []byte {
+	builder := strings.Builder{}
+	builder.WriteString("From: " + m.From + "\r\n")
+	builder.WriteString("To: " + msg.To + "\r\n")
+	builder.WriteString("Subject: " + msg.Subject + "\r\n")
+	builder.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(builder.String())
+}
+
+// LogMailer doesn't send anything and just logs the message, useful in development when there's no mail server
+type LogMailer struct{}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSMTPMailerBuildMessage(t *testing.T) {
+	m := &SMTPMailer{From: "noreply@steam-lens.local"}
+
+	raw := string(m.buildMessage(Message{
+		To:      "user1@domain.com",
+		Subject: "Reset your password",
+		Body:    "line one\nline two",
+	}))
+
+	headers, body, found := strings.Cut(raw, "\r\n\r\n")
+	if !found {
+		t.Fatal("Expected a blank line between headers and body")
+	}
+
+	for _, header := range []string{"From: noreply@steam-lens.local", "To: user1@domain.com", "Subject: Reset your password"} {
+		if !strings.Contains(headers, header) {
+			t.Errorf("Expected header %q in %q", header, headers)
+		}
+	}
+
+	if body != "line one\r\nline two" {
+		t.Errorf("Expected body lines to use CRLF, got %q", body)
+	}
+}
@@ -0,0 +1,214 @@
+// Package scheduler runs background jobs on an interval. Every replica runs the same scheduler and a lease
+// stored in Postgres decides which one of them actually runs a job each time it comes due
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/database"
+)
+
+// Jobs wait this long after start up before their first run so the server can finish booting
+const startupDelay = 30 * time.Second
+
+type Job struct {
+	Name string
+	// Time between runs, the lease is held this long so the job runs at most once per interval across replicas
+	Interval time.Duration
+	// Up to this much random time is added to every wait so replicas don't all wake up together
+	Jitter time.Duration
+	// Defaults to the interval, a run taking longer is cancelled so it can't overlap the next one
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+type Run struct {
+	JobName    string
+	Owner      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Err        error
+}
+
+// Store keeps the leases and run history shared by every replica
+type Store interface {
+	AcquireLease(ctx context.Context, name, owner string, until time.Time) (bool, error)
+	RecordRun(ctx context.Context, run Run) error
+}
+
+type Scheduler struct {
+	store Store
+	owner string
+	jobs  []Job
+	wg    sync.WaitGroup
+}
+
+// The owner identifies this replica in leases and run records, it needs to be unique per process
+func New(store Store, owner string) *Scheduler {
+	return &Scheduler{
+		store: store,
+		owner: owner,
+	}
+}
+
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("job needs a name and a run function")
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("job '%s' needs a positive interval", job.Name)
+	}
+	for _, existing := range s.jobs {
+		if existing.Name == job.Name {
+			return fmt.Errorf("job '%s' is already scheduled", job.Name)
+		}
+	}
+
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+func (s *Scheduler) Jobs() []Job {
+	return append([]Job{}, s.jobs...)
+}
+
+// Starts a goroutine per job that keeps running until ctx is cancelled, Wait blocks until they've stopped
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+}
+
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	timer := time.NewTimer(startupDelay + jitter(job.Jitter))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			s.RunIfDue(ctx, job)
+			timer.Reset(nextDelay(job.Interval, job.Jitter))
+		}
+	}
+}
+
+// Runs the job when this replica gets its lease, otherwise another replica has already run it this interval.
+// Returns whether the job ran
+func (s *Scheduler) RunIfDue(ctx context.Context, job Job) bool {
+	startedAt := time.Now().UTC()
+
+	acquired, err := s.store.AcquireLease(ctx, job.Name, s.owner, startedAt.Add(job.Interval))
+	if err != nil {
+		log.Printf("Unable to acquire lease for job '%s': %v\n", job.Name, err)
+		return false
+	}
+	if !acquired {
+		return false
+	}
+
+	timeout := job.Timeout
+	if timeout <= 0 || timeout > job.Interval {
+		timeout = job.Interval
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Printf("Running job '%s'\n", job.Name)
+	runErr := runSafely(runCtx, job)
+	finishedAt := time.Now().UTC()
+	if runErr != nil {
+		log.Printf("Job '%s' failed after %s: %v\n", job.Name, finishedAt.Sub(startedAt), runErr)
+	} else {
+		log.Printf("Job '%s' finished in %s\n", job.Name, finishedAt.Sub(startedAt))
+	}
+
+	// The run is recorded even when the server is shutting down, so it uses its own context
+	recordCtx, cancelRecord := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelRecord()
+	err = s.store.RecordRun(recordCtx, Run{
+		JobName:    job.Name,
+		Owner:      s.owner,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Err:        runErr,
+	})
+	if err != nil {
+		log.Printf("Unable to record run of job '%s': %v\n", job.Name, err)
+	}
+
+	return true
+}
+
+// A panicking job shouldn't take the whole server down with it
+func runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func nextDelay(interval, maxJitter time.Duration) time.Duration {
+	return interval + jitter(maxJitter)
+}
+
+func jitter(maxJitter time.Duration) time.Duration {
+	if maxJitter <= 0 {
+		return 0
+	}
+	return rand.N(maxJitter)
+}
+
+// PostgresStore keeps leases in job_leases and the run history in job_runs
+type PostgresStore struct {
+	DB *database.Queries
+}
+
+func (store PostgresStore) AcquireLease(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	_, err := store.DB.AcquireJobLease(ctx, database.AcquireJobLeaseParams{
+		Name:        name,
+		Owner:       owner,
+		LeasedUntil: until,
+		Now:         time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (store PostgresStore) RecordRun(ctx context.Context, run Run) error {
+	runErr := sql.NullString{}
+	if run.Err != nil {
+		runErr = sql.NullString{String: run.Err.Error(), Valid: true}
+	}
+
+	return store.DB.RecordJobRun(ctx, database.RecordJobRunParams{
+		JobName:    run.JobName,
+		Owner:      run.Owner,
+		StartedAt:  run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		Error:      runErr,
+	})
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	mu     sync.Mutex
+	leases map[string]time.Time
+	runs   []Run
+}
+
+func (store *memoryStore) AcquireLease(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.leases == nil {
+		store.leases = map[string]time.Time{}
+	}
+	if leasedUntil, ok := store.leases[name]; ok && leasedUntil.After(time.Now()) {
+		return false, nil
+	}
+	store.leases[name] = until
+	return true, nil
+}
+
+func (store *memoryStore) RecordRun(ctx context.Context, run Run) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.runs = append(store.runs, run)
+	return nil
+}
+
+func TestRunIfDueOnlyOneReplicaRuns(t *testing.T) {
+	store := &memoryStore{}
+	replicaA := New(store, "a")
+	replicaB := New(store, "b")
+
+	runs := 0
+	job := Job{
+		Name:     "warm",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			runs++
+			return nil
+		},
+	}
+
+	if !replicaA.RunIfDue(context.Background(), job) {
+		t.Fatal("expected the first replica to get the lease")
+	}
+	if replicaB.RunIfDue(context.Background(), job) {
+		t.Error("expected the second replica to skip the job while the lease is held")
+	}
+	if runs != 1 {
+		t.Errorf("expected the job to run once, got %d", runs)
+	}
+	if len(store.runs) != 1 || store.runs[0].Owner != "a" {
+		t.Errorf("expected one run recorded for replica a, got %+v", store.runs)
+	}
+}
+
+func TestRunIfDueRecordsErrorsAndPanics(t *testing.T) {
+	store := &memoryStore{}
+	scheduler := New(store, "a")
+
+	scheduler.RunIfDue(context.Background(), Job{
+		Name:     "failing",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			return errors.New("steam is down")
+		},
+	})
+	scheduler.RunIfDue(context.Background(), Job{
+		Name:     "panicking",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			panic("oops")
+		},
+	})
+
+	if len(store.runs) != 2 {
+		t.Fatalf("expected 2 recorded runs, got %d", len(store.runs))
+	}
+	if store.runs[0].Err == nil || store.runs[0].Err.Error() != "steam is down" {
+		t.Errorf("expected the job's error to be recorded, got %v", store.runs[0].Err)
+	}
+	if store.runs[1].Err == nil || !strings.Contains(store.runs[1].Err.Error(), "oops") {
+		t.Errorf("expected the panic to be recorded as an error, got %v", store.runs[1].Err)
+	}
+}
+
+func TestAddRejectsInvalidJobs(t *testing.T) {
+	scheduler := New(&memoryStore{}, "a")
+	run := func(ctx context.Context) error { return nil }
+
+	if err := scheduler.Add(Job{Name: "warm", Interval: time.Hour, Run: run}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := scheduler.Add(Job{Name: "warm", Interval: time.Hour, Run: run}); err == nil {
+		t.Error("expected duplicate job names to be rejected")
+	}
+	if err := scheduler.Add(Job{Name: "never", Run: run}); err == nil {
+		t.Error("expected a job without an interval to be rejected")
+	}
+}
+
+func TestNextDelayStaysWithinJitter(t *testing.T) {
+	for range 100 {
+		delay := nextDelay(time.Hour, time.Minute)
+		if delay < time.Hour || delay >= time.Hour+time.Minute {
+			t.Fatalf("delay %s outside of [1h, 1h1m)", delay)
+		}
+	}
+	if delay := nextDelay(time.Hour, 0); delay != time.Hour {
+		t.Errorf("expected no jitter, got %s", delay)
+	}
+}
@@ -0,0 +1,133 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 64
+	minPasswordLength = 8
+	// bcrypt silently ignores anything past 72 bytes, so longer passwords would give a false sense of security
+	maxPasswordBytes = 72
+
+	// SteamID64s for individual accounts are this base plus a 32 bit account ID, account ID 0 isn't valid
+	steamID64IndividualBase = 76561197960265728
+	steamID64IndividualMax  = steamID64IndividualBase + 1<<32 - 1
+)
+
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// Validator collects every problem with a request so they can all be reported at once,
+// only the first problem found for each field is kept
+type Validator struct {
+	Errors []FieldError
+}
+
+func New() *Validator {
+	return &Validator{Errors: []FieldError{}}
+}
+
+func (v *Validator) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+func (v *Validator) AddError(field, reason string) {
+	for _, fieldErr := range v.Errors {
+		if fieldErr.Field == field {
+			return
+		}
+	}
+	v.Errors = append(v.Errors, FieldError{Field: field, Reason: reason})
+}
+
+func (v *Validator) Check(ok bool, field, reason string) {
+	if !ok {
+		v.AddError(field, reason)
+	}
+}
+
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// Usernames are 3 to 64 characters of letters, numbers and . _ - @ + so email addresses can be used as usernames
+func (v *Validator) Username(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.AddError(field, "is required")
+		return
+	}
+
+	length := utf8.RuneCountInString(value)
+	v.Check(length >= minUsernameLength, field, "must be at least 3 characters")
+	v.Check(length <= maxUsernameLength, field, "must be at most 64 characters")
+	v.Check(strings.IndexFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._-@+", r)
+	}) == -1, field, "may only contain letters, numbers and . _ - @ +")
+}
+
+func (v *Validator) Password(field, value string) {
+	if value == "" {
+		v.AddError(field, "is required")
+		return
+	}
+
+	v.Check(utf8.RuneCountInString(value) >= minPasswordLength, field, "must be at least 8 characters")
+	v.Check(len(value) <= maxPasswordBytes, field, "must be at most 72 bytes")
+	v.Check(strings.TrimSpace(value) != "", field, "can't be only whitespace")
+}
+
+func (v *Validator) SteamID64(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.AddError(field, "is required")
+		return
+	}
+
+	v.Check(IsSteamID64(value), field, "must be a 17 digit SteamID64 of an individual Steam account")
+}
+
+// A list of distinct SteamID64s with between minCount and maxCount entries, bad entries are reported as field[i]
+func (v *Validator) SteamIDList(field string, values []string, minCount, maxCount int) {
+	v.Check(len(values) >= minCount, field, fmt.Sprintf("must have at least %d Steam IDs", minCount))
+	v.Check(len(values) <= maxCount, field, fmt.Sprintf("must have at most %d Steam IDs", maxCount))
+
+	seen := map[string]bool{}
+	for i, value := range values {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		v.SteamID64(itemField, value)
+		v.Check(!seen[value], itemField, "is listed more than once")
+		seen[value] = true
+	}
+}
+
+func (v *Validator) Email(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.AddError(field, "is required")
+		return
+	}
+
+	address, err := mail.ParseAddress(value)
+	v.Check(err == nil && address.Name == "" && address.Address == strings.TrimSpace(value), field, "must be a valid email address")
+}
+
+// Reports whether s is the SteamID64 of an individual account, such as 76561197997096401
+func IsSteamID64(s string) bool {
+	if len(s) != 17 {
+		return false
+	}
+
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return id > steamID64IndividualBase && id <= steamID64IndividualMax
+}
@@ -0,0 +1,131 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsSteamID64(t *testing.T) {
+	tests := []struct {
+		name     string
+		steamID  string
+		expected bool
+	}{
+		{name: "Valid SteamID64", steamID: "76561197997096401", expected: true},
+		{name: "Highest individual account", steamID: "76561202255233023", expected: true},
+		{name: "Account ID 0", steamID: "76561197960265728", expected: false},
+		{name: "Past the individual range", steamID: "76561202255233024", expected: false},
+		{name: "Not an individual account", steamID: "90071996842377216", expected: false},
+		{name: "Too short", steamID: "7656119799709640", expected: false},
+		{name: "Not a number", steamID: "7656119799709640a", expected: false},
+		{name: "Signed number", steamID: "+7656119799709640", expected: false},
+		{name: "Empty", steamID: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSteamID64(tt.steamID); got != tt.expected {
+				t.Errorf("IsSteamID64(%q) = %v, expected %v", tt.steamID, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidatorCollectsFieldErrors(t *testing.T) {
+	v := New()
+	v.Username("username", "a b")
+	v.Password("password", "short")
+	v.SteamID64("steam_id", "not-a-steam-id")
+	v.Email("email", "user1@domain.com")
+
+	if v.Valid() {
+		t.Fatal("Expected validation to fail")
+	}
+
+	fields := map[string]string{}
+	for _, fieldErr := range v.Errors {
+		if _, exists := fields[fieldErr.Field]; exists {
+			t.Errorf("Expected only one error for %s", fieldErr.Field)
+		}
+		fields[fieldErr.Field] = fieldErr.Reason
+	}
+
+	for _, field := range []string{"username", "password", "steam_id"} {
+		if _, exists := fields[field]; !exists {
+			t.Errorf("Expected an error for %s", field)
+		}
+	}
+	if _, exists := fields["email"]; exists {
+		t.Errorf("Did not expect an error for a valid email, got %q", fields["email"])
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{name: "Valid password", password: "Password123", valid: true},
+		{name: "Too short", password: "Pass1", valid: false},
+		{name: "Only whitespace", password: "            ", valid: false},
+		{name: "Over 72 bytes", password: strings.Repeat("a", 73), valid: false},
+		{name: "Empty", password: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			v.Password("password", tt.password)
+			if v.Valid() != tt.valid {
+				t.Errorf("Expected valid=%v, got errors %+v", tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestUsernameRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		valid    bool
+	}{
+		{name: "Email address", username: "user1@domain.com", valid: true},
+		{name: "Plain name", username: "rock_and_stone", valid: true},
+		{name: "Too short", username: "ab", valid: false},
+		{name: "Has spaces", username: "two words", valid: false},
+		{name: "Too long", username: strings.Repeat("a", 65), valid: false},
+		{name: "Empty", username: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			v.Username("username", tt.username)
+			if v.Valid() != tt.valid {
+				t.Errorf("Expected valid=%v, got errors %+v", tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestValidatorSteamIDList(t *testing.T) {
+	v := New()
+	v.SteamIDList("steam_ids", []string{"76561197997096401", "bad", "76561197997096401"}, 2, 16)
+
+	expected := map[string]bool{"steam_ids[1]": true, "steam_ids[2]": true}
+	if len(v.Errors) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), v.Errors)
+	}
+	for _, fieldErr := range v.Errors {
+		if !expected[fieldErr.Field] {
+			t.Errorf("unexpected error for field %q: %s", fieldErr.Field, fieldErr.Reason)
+		}
+	}
+
+	v = New()
+	v.SteamIDList("steam_ids", []string{"76561197997096401"}, 2, 16)
+	if v.Valid() || v.Errors[0].Field != "steam_ids" {
+		t.Errorf("expected a count error on steam_ids, got %v", v.Errors)
+	}
+}
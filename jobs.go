@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/scheduler"
+	"github.com/google/uuid"
+)
+
+const (
+	jobWarmSteamCaches    = "warm_steam_caches"
+	jobScheduledSnapshots = "scheduled_snapshots"
+	jobSamplePlaytime     = "sample_playtime"
+	jobFetchAppDetails    = "fetch_app_details"
+	jobPruneJobRuns       = "prune_job_runs"
+
+	// Pause between Steam requests made by jobs so warming the caches doesn't use up the API key's rate limit
+	jobSteamRequestSpacing = 250 * time.Millisecond
+	// Friends of every registered user can add up to a lot of Steam IDs, jobs leave out anything past this
+	maxTrackedSteamIDs = 1000
+
+	// Store lookups per run of fetch_app_details, spaced out they take under 4 minutes and stay inside the store API's limit
+	appDetailsPerJobRun = 150
+
+	jobRunRetention = 30 * 24 * time.Hour
+)
+
+// Registers every background job, the owner names this process in the job leases and run history
+func (cfg *config) newScheduler() (*scheduler.Scheduler, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	owner := fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+
+	jobs := scheduler.New(scheduler.PostgresStore{DB: cfg.db}, owner)
+
+	for _, job := range []scheduler.Job{
+		{
+			Name:     jobWarmSteamCaches,
+			Interval: 45 * time.Minute,
+			Jitter:   5 * time.Minute,
+			Run:      cfg.warmSteamCaches,
+		},
+		{
+			Name:     jobScheduledSnapshots,
+			Interval: 24 * time.Hour,
+			Jitter:   30 * time.Minute,
+			Timeout:  2 * time.Hour,
+			Run:      cfg.takeScheduledSnapshots,
+		},
+		{
+			Name:     jobSamplePlaytime,
+			Interval: 3 * time.Hour,
+			Jitter:   15 * time.Minute,
+			Run:      cfg.samplePlaytimeJob,
+		},
+		{
+			Name:     jobFetchAppDetails,
+			Interval: 30 * time.Minute,
+			Jitter:   5 * time.Minute,
+			Run:      cfg.fetchAppDetails,
+		},
+		{
+			Name:     jobPruneJobRuns,
+			Interval: 24 * time.Hour,
+			Jitter:   time.Hour,
+			Timeout:  time.Minute,
+			Run:      cfg.pruneJobRuns,
+		},
+	} {
+		if err := jobs.Add(job); err != nil {
+			return nil, err
+		}
+	}
+
+	return jobs, nil
+}
+
+// Refreshes the owned games and player summaries of every registered user and their friends so the pages
+// people open most are already cached. Owned games are fetched even when cached so they don't expire between runs
+func (cfg *config) warmSteamCaches(ctx context.Context) error {
+	failures := jobFailures{}
+	toWarm, err := cfg.trackedSteamIDs(ctx, &failures)
+	if err != nil {
+		return err
+	}
+
+	if err := waitForNextSteamRequest(ctx); err != nil {
+		return err
+	}
+	if _, err := cfg.steamAPI.GetPlayerSummaries(toWarm); err != nil {
+		failures.add("player summaries", err)
+	}
+
+	for _, steamID := range toWarm {
+		if err := waitForNextSteamRequest(ctx); err != nil {
+			return err
+		}
+		if _, err := cfg.steamAPI.FetchOwnedGames(steamID); err != nil {
+			failures.add(steamID, err)
+		}
+	}
+
+	log.Printf("Warmed Steam caches for %d Steam IDs\n", len(toWarm))
+	return failures.err()
+}
+
+// Samples the playtime of every registered user and their friends for the playtime reports
+func (cfg *config) samplePlaytimeJob(ctx context.Context) error {
+	failures := jobFailures{}
+	steamIDs, err := cfg.trackedSteamIDs(ctx, &failures)
+	if err != nil {
+		return err
+	}
+
+	for _, steamID := range steamIDs {
+		if err := waitForNextSteamRequest(ctx); err != nil {
+			return err
+		}
+		if err := cfg.samplePlaytime(ctx, steamID); err != nil {
+			failures.add(steamID, err)
+		}
+	}
+
+	log.Printf("Sampled playtime for %d Steam IDs\n", len(steamIDs))
+	return failures.err()
+}
+
+// Registered users' Steam IDs followed by their friends', friend lists that can't be loaded are counted as failures
+func (cfg *config) trackedSteamIDs(ctx context.Context, failures *jobFailures) ([]string, error) {
+	steamIDs, err := cfg.db.ListActiveSteamIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	tracked := []string{}
+	add := func(steamID string) {
+		if !seen[steamID] && len(tracked) < maxTrackedSteamIDs {
+			seen[steamID] = true
+			tracked = append(tracked, steamID)
+		}
+	}
+
+	for _, steamID := range steamIDs {
+		add(steamID)
+	}
+	for _, steamID := range steamIDs {
+		if err := waitForNextSteamRequest(ctx); err != nil {
+			return nil, err
+		}
+		friendList, err := cfg.steamAPI.GetFriendList(steamID)
+		if err != nil {
+			failures.add(steamID, err)
+			continue
+		}
+		for _, friend := range friendList.Friends {
+			add(friend.SteamID)
+		}
+	}
+
+	return tracked, nil
+}
+
+// Looks up store metadata for games in the libraries of registered users and then their friends a batch at a time,
+// so filters and taste profiles have data to work with without requests waiting on the store API
+func (cfg *config) fetchAppDetails(ctx context.Context) error {
+	failures := jobFailures{}
+	steamIDs, err := cfg.trackedSteamIDs(ctx, &failures)
+	if err != nil {
+		return err
+	}
+
+	seen := map[int]bool{}
+	appIDs := []int{}
+	for _, steamID := range steamIDs {
+		if err := waitForNextSteamRequest(ctx); err != nil {
+			return err
+		}
+		ownedGames, err := cfg.steamAPI.GetOwnedGames(steamID)
+		if err != nil {
+			failures.add(steamID, err)
+			continue
+		}
+		for _, game := range ownedGames.Games {
+			if !seen[game.AppID] {
+				seen[game.AppID] = true
+				appIDs = append(appIDs, game.AppID)
+			}
+		}
+	}
+
+	details, err := cfg.steamAPI.GetAppDetails(ctx, appIDs, appDetailsPerJobRun)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Store details known for %d of %d games\n", len(details), len(appIDs))
+	return failures.err()
+}
+
+// Takes the daily snapshot of every registered user's library, private libraries are skipped
+func (cfg *config) takeScheduledSnapshots(ctx context.Context) error {
+	steamIDs, err := cfg.db.ListActiveSteamIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	failures := jobFailures{}
+	for _, steamID := range steamIDs {
+		if err := waitForNextSteamRequest(ctx); err != nil {
+			return err
+		}
+		_, _, err := cfg.takeLibrarySnapshot(ctx, steamID, snapshotSourceScheduled, uuid.NullUUID{})
+		if err != nil && !errors.Is(err, errLibraryNotVisible) {
+			failures.add(steamID, err)
+		}
+	}
+
+	return failures.err()
+}
+
+func (cfg *config) pruneJobRuns(ctx context.Context) error {
+	deleted, err := cfg.db.DeleteJobRunsBefore(ctx, time.Now().UTC().Add(-jobRunRetention))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Pruned %d old job runs\n", deleted)
+	return nil
+}
+
+func waitForNextSteamRequest(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(jobSteamRequestSpacing):
+		return nil
+	}
+}
+
+// One Steam ID failing shouldn't stop a job, the failures are counted and reported together at the end
+type jobFailures struct {
+	count int
+	last  error
+}
+
+func (failures *jobFailures) add(subject string, err error) {
+	failures.count++
+	failures.last = fmt.Errorf("%s: %w", subject, err)
+}
+
+func (failures *jobFailures) err() error {
+	if failures.count == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d Steam requests failed, last error: %w", failures.count, failures.last)
+}
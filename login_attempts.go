@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/steam-lens/internal/database"
+)
+
+const (
+	loginScopeUsername = "username"
+	loginScopeIP       = "ip"
+
+	maxFailedLoginsPerUsername = 5
+	maxFailedLoginsPerIP       = 20
+
+	failedLoginWindow    = 15 * time.Minute
+	loginLockoutDuration = 15 * time.Minute
+	baseFailedLoginDelay = 250 * time.Millisecond
+	maxFailedLoginDelay  = 4 * time.Second
+)
+
+// A login attempt is tracked both against the username it tried and the IP it came from,
+// so guessing many passwords for one account and spraying one password across accounts are both throttled
+type loginKey struct {
+	scope      string
+	identifier string
+	limit      int32
+}
+
+func loginKeys(username, ip string) []loginKey {
+	return []loginKey{
+		{scope: loginScopeUsername, identifier: username, limit: maxFailedLoginsPerUsername},
+		{scope: loginScopeIP, identifier: ip, limit: maxFailedLoginsPerIP},
+	}
+}
+
+// Returns the latest time any of the keys is locked until, or the zero time if none are locked
+func (cfg *config) loginLockedUntil(ctx context.Context, keys []loginKey) (time.Time, error) {
+	lockedUntil := time.Time{}
+	now := time.Now().UTC()
+
+	for _, key := range keys {
+		attempt, err := cfg.db.GetLoginAttempt(ctx, database.GetLoginAttemptParams{
+			Scope:      key.scope,
+			Identifier: key.identifier,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if attempt.LockedUntil.Valid && attempt.LockedUntil.Time.After(now) && attempt.LockedUntil.Time.After(lockedUntil) {
+			lockedUntil = attempt.LockedUntil.Time
+		}
+	}
+
+	return lockedUntil, nil
+}
+
+// Records a failed login against every key, locking any key that went over its limit.
+// Returns how long the response should be delayed based on the worst offending key
+func (cfg *config) recordFailedLogin(ctx context.Context, keys []loginKey) (time.Duration, error) {
+	now := time.Now().UTC()
+	var highestCount int32
+
+	for _, key := range keys {
+		attempt, err := cfg.db.RecordFailedLogin(ctx, database.RecordFailedLoginParams{
+			Scope:       key.scope,
+			Identifier:  key.identifier,
+			FailedAt:    now,
+			WindowStart: now.Add(-failedLoginWindow),
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if attempt.FailedCount >= key.limit {
+			err = cfg.db.LockLoginAttempt(ctx, database.LockLoginAttemptParams{
+				Scope:       key.scope,
+				Identifier:  key.identifier,
+				LockedUntil: sql.NullTime{Time: now.Add(loginLockoutDuration), Valid: true},
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		highestCount = max(highestCount, attempt.FailedCount)
+	}
+
+	return failedLoginDelay(highestCount), nil
+}
+
+// A successful login only clears the username's failures, the IP keeps its count so an attacker
+// can't reset their own throttle by logging into an account they control
+func (cfg *config) clearFailedLogins(ctx context.Context, username string) error {
+	return cfg.db.ClearLoginAttempts(ctx, database.ClearLoginAttemptsParams{
+		Scope:      loginScopeUsername,
+		Identifier: username,
+	})
+}
+
+// Delay doubles with each consecutive failure, starting at baseFailedLoginDelay and capped at maxFailedLoginDelay
+func failedLoginDelay(failedCount int32) time.Duration {
+	if failedCount <= 0 {
+		return 0
+	}
+
+	delay := baseFailedLoginDelay
+	for i := int32(1); i < failedCount && delay < maxFailedLoginDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxFailedLoginDelay)
+}
+
+// Helper function to wait out a delay unless the client goes away first
+func sleepWithContext(ctx context.Context, delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
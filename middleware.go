@@ -3,31 +3,136 @@ package main
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/Khazz0r/steam-lens/internal/api"
-	"github.com/Khazz0r/steam-lens/internal/auth"
 	"github.com/google/uuid"
 )
 
 type contextKey string
 
 const userIDContextKey = contextKey("userID")
+const userRoleContextKey = contextKey("userRole")
+
+const (
+	roleUser  = "user"
+	roleAdmin = "admin"
+)
 
 func (cfg *config) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Everything behind this middleware is authenticated by cookie, which the browser attaches
+		// to forged cross-site requests as well, so state-changing requests need the CSRF token too
+		err := cfg.checkCSRF(req)
+		if err != nil {
+			api.RespondWithAppError(w, api.NewError(http.StatusForbidden, api.CodeCSRFFailed, "Missing or invalid CSRF token", err))
+			return
+		}
+
 		cookie, err := req.Cookie("JWT_token")
 		if err != nil {
 			api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to do this, missing the proper refresh_token", err)
 			return
 		}
 
-		userID, err := auth.ValidateJWT(cookie.Value, cfg.jwtSecret)
-		if err != nil || userID == uuid.Nil {
+		claims, err := cfg.jwtKeys.ValidateJWTClaims(cookie.Value)
+		if err != nil || claims.UserID == uuid.Nil {
 			api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to do this, invalid token provided", err)
 			return
 		}
 
-		context := context.WithValue(req.Context(), userIDContextKey, userID)
-		next.ServeHTTP(w, req.WithContext(context))
+		// The user is looked up on every request so disabling an account or forcing a logout
+		// takes effect right away instead of when the access token expires
+		user, err := cfg.db.GetUserByID(req.Context(), claims.UserID)
+		if err != nil {
+			api.RespondWithError(w, http.StatusUnauthorized, "Not authorized to do this, user no longer exists", err)
+			return
+		}
+		if user.DisabledAt.Valid {
+			api.RespondWithError(w, http.StatusForbidden, "This account has been disabled", nil)
+			return
+		}
+		// JWT timestamps only have second precision, so tokens issued in the same second as the revocation
+		// are rejected too
+		if user.SessionsRevokedAt.Valid && !claims.IssuedAt.After(user.SessionsRevokedAt.Time.Truncate(time.Second)) {
+			api.RespondWithError(w, http.StatusUnauthorized, "Session has been revoked, please log in again", nil)
+			return
+		}
+
+		ctx := context.WithValue(req.Context(), userIDContextKey, user.ID)
+		ctx = context.WithValue(ctx, userRoleContextKey, user.Role)
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// RequireRole authenticates the request with AuthMiddleware and then only lets it through if the user has the role
+func (cfg *config) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return cfg.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			userRole, exists := req.Context().Value(userRoleContextKey).(string)
+			if !exists || userRole != role {
+				api.RespondWithError(w, http.StatusForbidden, "Not authorized to perform this action", nil)
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		}))
+	}
+}
+
+// Player details that take a Steam request per player can keep a request busy for a while, so
+// asking for levels or badges needs a login. Summaries on their own and bans stay public
+func (cfg *config) RequireLoginForPlayerDetails(next http.Handler) http.Handler {
+	authenticated := cfg.AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		includes, err := api.ParsePlayerIncludes(req.URL.Query().Get("include"))
+		if err == nil && includes.PerPlayer() {
+			authenticated.ServeHTTP(w, req)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Game filters and taste rankings can wait on the store API for several seconds, so they need a login too
+func (cfg *config) RequireLoginForStoreDetails(next http.Handler) http.Handler {
+	authenticated := cfg.AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if api.UsesStoreDetails(req.URL.Query()) {
+			authenticated.ServeHTTP(w, req)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Tags every request with an ID that's echoed back in the X-Request-ID header and in error
+// responses, so a client report can be matched to the server logs. A sane ID sent by the
+// client (e.g. from a proxy) is kept, anything else is replaced
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requestID := req.Header.Get(api.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set(api.RequestIDHeader, requestID)
+		next.ServeHTTP(w, req)
 	})
 }
+
+// Helper function to keep client supplied request IDs short and safe to put in logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		isAlnum := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlnum && r != '-' && r != '_' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
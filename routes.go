@@ -12,10 +12,48 @@ func (cfg *config) routesV1() http.Handler {
 
 	router.Post("/users/create", cfg.handlerUserCreate)
 	router.Post("/users/login", cfg.handlerLogin)
-	router.Post("/users/delete", cfg.handlerDeleteAllUsers)
+	router.Post("/users/login/2fa", cfg.handlerLoginMFA)
+	router.With(cfg.RequireRole(roleAdmin)).Post("/users/delete", cfg.handlerDeleteAllUsers)
+	router.Post("/users/password-reset/request", cfg.handlerRequestPasswordReset)
+	router.Post("/users/password-reset/confirm", cfg.handlerResetPassword)
+	router.Post("/users/email/verify", cfg.handlerVerifyEmail)
+	router.Get("/users/csrf", cfg.handlerCSRFToken)
 	router.With(cfg.AuthMiddleware).Post("/users/logout", cfg.handlerLogout)
 	router.With(cfg.AuthMiddleware).Get("/users/me", cfg.handlerGetMe)
 	router.With(cfg.AuthMiddleware).Patch("/users/me", cfg.handlerUpdateUser)
+	router.With(cfg.AuthMiddleware).Delete("/users/me", cfg.handlerDeleteMe)
+	router.With(cfg.AuthMiddleware).Get("/users/me/export", cfg.handlerExportMe)
+	router.With(cfg.AuthMiddleware).Get("/users/me/activity", cfg.handlerGetMyActivity)
+	router.With(cfg.AuthMiddleware).Put("/users/me/email", cfg.handlerSetEmail)
+	router.With(cfg.AuthMiddleware).Post("/users/me/2fa/enroll", cfg.handlerMFAEnroll)
+	router.With(cfg.AuthMiddleware).Post("/users/me/2fa/confirm", cfg.handlerMFAConfirm)
+	router.With(cfg.AuthMiddleware).Post("/users/me/2fa/disable", cfg.handlerMFADisable)
+	router.With(cfg.AuthMiddleware).Get("/users/me/squads", cfg.handlerListSquads)
+	router.With(cfg.AuthMiddleware).Post("/users/me/squads", cfg.handlerCreateSquad)
+	router.With(cfg.AuthMiddleware).Get("/users/me/squads/{squadID}", cfg.handlerGetSquad)
+	router.With(cfg.AuthMiddleware).Put("/users/me/squads/{squadID}", cfg.handlerUpdateSquad)
+	router.With(cfg.AuthMiddleware).Delete("/users/me/squads/{squadID}", cfg.handlerDeleteSquad)
+	router.With(cfg.AuthMiddleware).Get("/users/me/squads/{squadID}/games", cfg.handlerSquadGames)
+	router.With(cfg.AuthMiddleware).Post("/snapshots", cfg.handlerCreateSnapshot)
+	router.With(cfg.AuthMiddleware).Get("/snapshots", cfg.handlerListSnapshots)
+	router.With(cfg.AuthMiddleware).Get("/snapshots/diff", cfg.handlerDiffSnapshots)
+	router.With(cfg.AuthMiddleware).Get("/snapshots/{snapshotID}", cfg.handlerGetSnapshot)
+	router.With(cfg.AuthMiddleware).Get("/playtime/weekly", cfg.handlerWeeklyPlaytime)
+	router.With(cfg.AuthMiddleware).Get("/playtime/monthly", cfg.handlerMonthlyPlaytime)
+	router.With(cfg.AuthMiddleware).Get("/playtime/top", cfg.handlerTopPlaytimeGames)
+	router.With(cfg.AuthMiddleware).Get("/playtime/friends", cfg.handlerFriendsPlaytime)
+
+	router.Route("/admin", func(admin chi.Router) {
+		admin.Use(cfg.RequireRole(roleAdmin))
+		admin.Get("/users", cfg.handlerAdminListUsers)
+		admin.Post("/users/{userID}/disable", cfg.handlerAdminDisableUser)
+		admin.Post("/users/{userID}/enable", cfg.handlerAdminEnableUser)
+		admin.Post("/users/{userID}/logout", cfg.handlerAdminLogoutUser)
+		admin.Delete("/users/{userID}", cfg.handlerAdminDeleteUser)
+		admin.Get("/audit-events", cfg.handlerAdminListAuditEvents)
+		admin.Get("/jobs", cfg.handlerAdminListJobs)
+		admin.Get("/jobs/{jobName}/runs", cfg.handlerAdminListJobRuns)
+	})
 
 	return router
 }
@@ -24,11 +62,17 @@ func (cfg *config) routesV1() http.Handler {
 func (cfg *config) routesAPI() http.Handler {
 	router := chi.NewRouter()
 
-	router.Get("/player-summaries", cfg.steamAPI.HandlerGetPlayerSummaries)
-	router.Get("/friends", cfg.steamAPI.HandlerGetFriendList)
-	router.Get("/games", cfg.steamAPI.HandlerGetOwnedGames)
+	router.With(cfg.RequireLoginForPlayerDetails).Get("/player-summaries", cfg.steamAPI.HandlerGetPlayerSummaries)
+	router.With(cfg.RequireLoginForPlayerDetails).Get("/friends", cfg.steamAPI.HandlerGetFriendList)
+	router.With(cfg.RequireLoginForStoreDetails).Get("/games", cfg.steamAPI.HandlerGetOwnedGames)
+	router.Get("/recently-played", cfg.steamAPI.HandlerGetRecentlyPlayedGames)
+	router.With(cfg.AuthMiddleware).Get("/taste", cfg.steamAPI.HandlerGetTasteProfile)
 	router.Get("/achievements", cfg.steamAPI.HandlerGetPlayerAchievements)
-	router.Get("/friends/matchGames", cfg.steamAPI.HandlerMatchedGamesRanking)
+	router.With(cfg.RequireLoginForStoreDetails).Get("/friends/matchGames", cfg.steamAPI.HandlerMatchedGamesRanking)
+	router.With(cfg.AuthMiddleware).Get("/friends/activity", cfg.steamAPI.HandlerFriendActivity)
+	router.Get("/friends/online", cfg.steamAPI.HandlerOnlineFriends)
+	router.With(cfg.AuthMiddleware).Get("/friends/discover", cfg.steamAPI.HandlerDiscoverFriends)
+	router.With(cfg.AuthMiddleware).Get("/friends/recommendations", cfg.steamAPI.HandlerFriendRecommendations)
 	router.Get("/compare-achievements", cfg.steamAPI.HandlerCompareAchievements)
 
 	return router
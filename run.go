@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,15 +17,25 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/auth"
 	"github.com/Khazz0r/steam-lens/internal/database"
+	"github.com/Khazz0r/steam-lens/internal/mailer"
+	"github.com/Khazz0r/steam-lens/internal/scheduler"
 	_ "github.com/lib/pq"
 )
 
 type config struct {
-	db        *database.Queries
-	platform  string
-	jwtSecret string
-	steamAPI  *api.ApiConfig
+	db             *database.Queries
+	sqlDB          *sql.DB
+	platform       string
+	jwtKeys        *auth.KeySet
+	passwords      *auth.PasswordHasher
+	steamAPI       *api.ApiConfig
+	mailer         mailer.Mailer
+	appBaseURL     string
+	allowedOrigins []string
+	jobs           *scheduler.Scheduler
+	jobsEnabled    bool
 }
 
 //go:embed static/*
@@ -36,8 +49,20 @@ func run() error {
 	platform := getEnvOrFail("PLATFORM")
 	dbURL := getEnvOrFail("DATABASE_URL")
 	port := getEnvOrFail("PORT")
-	jwtSecret := getEnvOrFail("JWTSECRET")
 	steamAPIKey := getEnvOrFail("STEAM_API_KEY")
+	storeAPIURL := getEnvOrDefault("STEAM_STORE_API_URL", api.DefaultStoreAPIURL)
+	appBaseURL := getEnvOrDefault("APP_BASE_URL", "http://localhost:3000")
+	allowedOrigins := parseAllowedOrigins(getEnvOrDefault("CORS_ALLOWED_ORIGINS", appBaseURL))
+
+	jwtKeys, err := loadJWTKeys()
+	if err != nil {
+		return fmt.Errorf("loading JWT keys: %w", err)
+	}
+
+	passwords, err := loadPasswordHasher()
+	if err != nil {
+		return fmt.Errorf("loading password hashing settings: %w", err)
+	}
 
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -45,11 +70,18 @@ func run() error {
 	}
 
 	cfg := &config{
-		db:        database.New(db),
-		platform:  platform,
-		jwtSecret: jwtSecret,
+		db:             database.New(db),
+		sqlDB:          db,
+		platform:       platform,
+		jwtKeys:        jwtKeys,
+		passwords:      passwords,
+		mailer:         newMailer(),
+		appBaseURL:     appBaseURL,
+		allowedOrigins: allowedOrigins,
 		steamAPI: &api.ApiConfig{
-			SteamApiKey: steamAPIKey,
+			SteamApiKey:     steamAPIKey,
+			StoreAPIURL:     storeAPIURL,
+			AppDetailsStore: appDetailsStore{db: database.New(db)},
 			PlayerCache: api.Cache[api.Player]{
 				Cache:     map[string]api.CachedData[api.Player]{},
 				RenewTime: 24 * time.Hour,
@@ -66,6 +98,34 @@ func run() error {
 				Cache:     map[string]api.CachedData[api.ConvertedPlayerAchievements]{},
 				RenewTime: 60 * time.Minute,
 			},
+			RecentlyPlayedCache: api.Cache[api.RecentlyPlayedGames]{
+				Cache:     map[string]api.CachedData[api.RecentlyPlayedGames]{},
+				RenewTime: 30 * time.Minute,
+			},
+			AppDetailsCache: api.Cache[api.AppDetails]{
+				Cache:     map[string]api.CachedData[api.AppDetails]{},
+				RenewTime: 24 * time.Hour,
+			},
+			BansCache: api.Cache[api.PlayerBans]{
+				Cache:     map[string]api.CachedData[api.PlayerBans]{},
+				RenewTime: 24 * time.Hour,
+			},
+			LevelCache: api.Cache[int]{
+				Cache:     map[string]api.CachedData[int]{},
+				RenewTime: 24 * time.Hour,
+			},
+			BadgesCache: api.Cache[api.Badges]{
+				Cache:     map[string]api.CachedData[api.Badges]{},
+				RenewTime: 24 * time.Hour,
+			},
+			PresenceCache: api.Cache[api.Player]{
+				Cache:     map[string]api.CachedData[api.Player]{},
+				RenewTime: 2 * time.Minute,
+			},
+			DiscoveryCache: api.Cache[api.Discovery]{
+				Cache:     map[string]api.CachedData[api.Discovery]{},
+				RenewTime: 6 * time.Hour,
+			},
 		},
 	}
 
@@ -90,17 +150,80 @@ func run() error {
 	}
 	ownedGamesCleaner.CacheCleanerStart()
 
+	recentlyPlayedCleaner := api.Cleaner[api.RecentlyPlayedGames]{
+		Name:     "RecentlyPlayedCache",
+		Cache:    &cfg.steamAPI.RecentlyPlayedCache,
+		Interval: 1 * time.Hour,
+	}
+	recentlyPlayedCleaner.CacheCleanerStart()
+
+	appDetailsCleaner := api.Cleaner[api.AppDetails]{
+		Name:     "AppDetailsCache",
+		Cache:    &cfg.steamAPI.AppDetailsCache,
+		Interval: 1 * time.Hour,
+	}
+	appDetailsCleaner.CacheCleanerStart()
+
+	bansCleaner := api.Cleaner[api.PlayerBans]{
+		Name:     "BansCache",
+		Cache:    &cfg.steamAPI.BansCache,
+		Interval: 1 * time.Hour,
+	}
+	bansCleaner.CacheCleanerStart()
+
+	levelCleaner := api.Cleaner[int]{
+		Name:     "LevelCache",
+		Cache:    &cfg.steamAPI.LevelCache,
+		Interval: 1 * time.Hour,
+	}
+	levelCleaner.CacheCleanerStart()
+
+	badgesCleaner := api.Cleaner[api.Badges]{
+		Name:     "BadgesCache",
+		Cache:    &cfg.steamAPI.BadgesCache,
+		Interval: 1 * time.Hour,
+	}
+	badgesCleaner.CacheCleanerStart()
+
+	presenceCleaner := api.Cleaner[api.Player]{
+		Name:     "PresenceCache",
+		Cache:    &cfg.steamAPI.PresenceCache,
+		Interval: 10 * time.Minute,
+	}
+	presenceCleaner.CacheCleanerStart()
+
+	discoveryCleaner := api.Cleaner[api.Discovery]{
+		Name:     "DiscoveryCache",
+		Cache:    &cfg.steamAPI.DiscoveryCache,
+		Interval: 1 * time.Hour,
+	}
+	discoveryCleaner.CacheCleanerStart()
+
+	cfg.jobs, err = cfg.newScheduler()
+	if err != nil {
+		return fmt.Errorf("scheduling jobs: %w", err)
+	}
+	// Replicas that should only serve requests can set JOBS_ENABLED=false, the others share the jobs through leases
+	cfg.jobsEnabled = getEnvOrDefault("JOBS_ENABLED", "true") != "false"
+	if cfg.jobsEnabled {
+		cfg.jobs.Start(context.Background())
+	} else {
+		fmt.Println("warning: JOBS_ENABLED is false, background jobs will not run on this replica")
+	}
+
 	router := chi.NewRouter()
+	router.Use(RequestIDMiddleware)
 	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
+		AllowedOrigins:   cfg.allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "PATCH"},
-		AllowedHeaders:   []string{"*"},
+		AllowedHeaders:   []string{"Accept", "Content-Type", "If-Match", csrfHeaderName, api.RequestIDHeader},
 		AllowCredentials: true,
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "ETag", api.RequestIDHeader},
 		MaxAge:           300,
 	}))
 
 	router.Get("/", serveIndex)
+	router.Get("/.well-known/jwks.json", cfg.handlerJWKS)
 	router.Mount("/v1", cfg.routesV1())
 	router.Mount("/api/steam", cfg.routesAPI())
 
@@ -122,6 +245,77 @@ func getEnvOrFail(key string) string {
 	return val
 }
 
+func getEnvOrDefault(key, fallback string) string {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	return val
+}
+
+// Tokens are signed with the RS256 or EdDSA key in JWT_SIGNING_KEY_FILE when it's set, and JWT_VERIFY_KEY_FILES
+// lists the public keys of older signing keys that are still accepted during a rotation.
+// Without a key file tokens fall back to HS256 with JWTSECRET, which can't be published in the JWKS
+func loadJWTKeys() (*auth.KeySet, error) {
+	issuer := getEnvOrDefault("JWT_ISSUER", "steam-lens")
+	audience := getEnvOrDefault("JWT_AUDIENCE", "steam-lens")
+
+	signingKeyPath := os.Getenv("JWT_SIGNING_KEY_FILE")
+	if signingKeyPath == "" {
+		return auth.NewHMACKeySet(getEnvOrFail("JWTSECRET"), issuer, audience), nil
+	}
+
+	verifyKeyPaths := []string{}
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFY_KEY_FILES"), ",") {
+		if strings.TrimSpace(path) != "" {
+			verifyKeyPaths = append(verifyKeyPaths, strings.TrimSpace(path))
+		}
+	}
+
+	return auth.LoadKeySetFromFiles(signingKeyPath, verifyKeyPaths, issuer, audience)
+}
+
+// Origins are a comma separated list like "https://steamlens.app,http://localhost:3000", wildcards aren't
+// allowed since credentials are sent cross-origin
+func parseAllowedOrigins(value string) []string {
+	origins := []string{}
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" || strings.Contains(origin, "*") {
+			continue
+		}
+		origins = append(origins, origin)
+	}
+	return origins
+}
+
+// New passwords are hashed with argon2id unless PASSWORD_HASH_ALGORITHM says bcrypt, existing hashes
+// made with other settings keep working and are upgraded the next time their user logs in
+func loadPasswordHasher() (*auth.PasswordHasher, error) {
+	bcryptCost, err := strconv.Atoi(getEnvOrDefault("BCRYPT_COST", "12"))
+	if err != nil {
+		return nil, fmt.Errorf("BCRYPT_COST must be a number: %w", err)
+	}
+
+	return auth.NewPasswordHasher(getEnvOrDefault("PASSWORD_HASH_ALGORITHM", auth.PasswordAlgorithmArgon2id), bcryptCost)
+}
+
+// MAILER picks how outgoing email is delivered, "smtp" sends through SMTP_HOST and anything else just logs it
+func newMailer() mailer.Mailer {
+	if os.Getenv("MAILER") != "smtp" {
+		fmt.Println("warning: MAILER is not set to smtp, emails will only be logged")
+		return &mailer.LogMailer{}
+	}
+
+	return &mailer.SMTPMailer{
+		Host:     getEnvOrFail("SMTP_HOST"),
+		Port:     getEnvOrDefault("SMTP_PORT", "1025"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getEnvOrDefault("MAIL_FROM", "noreply@steam-lens.local"),
+	}
+}
+
 func serveIndex(w http.ResponseWriter, req *http.Request) {
 	file, err := staticFiles.Open("static/index.html")
 	if err != nil {
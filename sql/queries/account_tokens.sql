@@ -0,0 +1,31 @@
+-- #nosec G101 -- only a hash of the token is stored
+-- name: CreateAccountToken :exec
+INSERT INTO account_tokens (token_hash, purpose, created_at, user_id, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- #nosec G101 -- false positive
+-- name: ConsumeAccountToken :one
+UPDATE account_tokens
+SET used_at = sqlc.arg(now)
+WHERE token_hash = sqlc.arg(token_hash)
+    AND purpose = sqlc.arg(purpose)
+    AND used_at IS NULL
+    AND expires_at > sqlc.arg(now)
+RETURNING user_id;
+
+-- #nosec G101 -- false positive
+-- name: DeleteAccountTokensForUser :exec
+DELETE FROM account_tokens
+WHERE user_id = $1 AND purpose = $2;
+
+-- #nosec G101 -- false positive
+-- name: ListAccountTokensForUser :many
+SELECT * FROM account_tokens
+WHERE user_id = $1
+ORDER BY created_at;
@@ -0,0 +1,26 @@
+-- name: UpsertAppDetails :exec
+INSERT INTO app_details (
+    app_id, found, name, type, is_free, genres, categories, multiplayer, coop,
+    price_currency, price_initial, price_final, discount_percent, fetched_at
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+ON CONFLICT (app_id) DO UPDATE
+SET found = EXCLUDED.found,
+    name = EXCLUDED.name,
+    type = EXCLUDED.type,
+    is_free = EXCLUDED.is_free,
+    genres = EXCLUDED.genres,
+    categories = EXCLUDED.categories,
+    multiplayer = EXCLUDED.multiplayer,
+    coop = EXCLUDED.coop,
+    price_currency = EXCLUDED.price_currency,
+    price_initial = EXCLUDED.price_initial,
+    price_final = EXCLUDED.price_final,
+    discount_percent = EXCLUDED.discount_percent,
+    fetched_at = EXCLUDED.fetched_at;
+
+-- name: ListAppDetails :many
+SELECT * FROM app_details
+WHERE app_id = ANY(sqlc.arg(app_ids)::int[]) AND fetched_at > sqlc.arg(fetched_after);
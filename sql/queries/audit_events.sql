@@ -0,0 +1,28 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (created_at, user_id, actor_id, event_type, ip, user_agent, details)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+);
+
+-- name: ListAuditEventsForUser :many
+SELECT * FROM audit_events
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id))
+    AND (sqlc.narg(event_type)::text IS NULL OR event_type = sqlc.narg(event_type))
+    AND (sqlc.narg(since)::timestamp IS NULL OR created_at >= sqlc.narg(since))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: PseudonymizeAuditEvents :one
+SELECT pseudonymize_audit_events(sqlc.arg(user_id)::uuid, sqlc.arg(username)::text);
@@ -0,0 +1,31 @@
+-- name: AcquireJobLease :one
+INSERT INTO job_leases (name, owner, leased_until)
+VALUES (sqlc.arg(name), sqlc.arg(owner), sqlc.arg(leased_until))
+ON CONFLICT (name) DO UPDATE
+SET owner = EXCLUDED.owner, leased_until = EXCLUDED.leased_until
+WHERE job_leases.leased_until <= sqlc.arg(now)
+RETURNING owner;
+
+-- name: RecordJobRun :exec
+INSERT INTO job_runs (job_name, owner, started_at, finished_at, error)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: ListLatestJobRuns :many
+SELECT DISTINCT ON (job_name) * FROM job_runs
+ORDER BY job_name, started_at DESC;
+
+-- name: ListJobRuns :many
+SELECT * FROM job_runs
+WHERE job_name = $1
+ORDER BY started_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: DeleteJobRunsBefore :execrows
+DELETE FROM job_runs
+WHERE started_at < $1;
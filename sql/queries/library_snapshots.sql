@@ -0,0 +1,50 @@
+-- name: CreateLibrarySnapshot :one
+INSERT INTO library_snapshots (id, steam_id, taken_at, game_count, source, created_by)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: AddLibrarySnapshotGames :exec
+INSERT INTO library_snapshot_games (snapshot_id, app_id, name, playtime_forever)
+SELECT
+    sqlc.arg(snapshot_id),
+    unnest(sqlc.arg(app_ids)::int[]),
+    unnest(sqlc.arg(names)::text[]),
+    unnest(sqlc.arg(playtimes)::int[]);
+
+-- name: GetLibrarySnapshot :one
+SELECT * FROM library_snapshots
+WHERE id = $1;
+
+-- name: GetLatestLibrarySnapshot :one
+SELECT * FROM library_snapshots
+WHERE steam_id = $1
+ORDER BY taken_at DESC
+LIMIT 1;
+
+-- name: ListLibrarySnapshots :many
+SELECT * FROM library_snapshots
+WHERE steam_id = $1
+ORDER BY taken_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListLibrarySnapshotsCreatedBy :many
+SELECT * FROM library_snapshots
+WHERE created_by = $1
+ORDER BY taken_at;
+
+-- name: DeleteUnclaimedLibrarySnapshots :exec
+DELETE FROM library_snapshots
+WHERE steam_id = $1
+    AND NOT EXISTS (SELECT 1 FROM users WHERE users.steam_id = $1);
+
+-- name: ListLibrarySnapshotGames :many
+SELECT * FROM library_snapshot_games
+WHERE snapshot_id = $1
+ORDER BY app_id;
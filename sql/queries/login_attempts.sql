@@ -0,0 +1,29 @@
+-- name: GetLoginAttempt :one
+SELECT * FROM login_attempts
+WHERE scope = $1 AND identifier = $2;
+
+-- name: RecordFailedLogin :one
+INSERT INTO login_attempts (scope, identifier, failed_count, last_failed_at)
+VALUES (
+    sqlc.arg(scope),
+    sqlc.arg(identifier),
+    1,
+    sqlc.arg(failed_at)
+)
+ON CONFLICT (scope, identifier) DO UPDATE
+SET
+    failed_count = CASE
+        WHEN login_attempts.last_failed_at < sqlc.arg(window_start) THEN 1
+        ELSE login_attempts.failed_count + 1
+    END,
+    last_failed_at = EXCLUDED.last_failed_at
+RETURNING *;
+
+-- name: LockLoginAttempt :exec
+UPDATE login_attempts
+SET locked_until = $3
+WHERE scope = $1 AND identifier = $2;
+
+-- name: ClearLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE scope = $1 AND identifier = $2;
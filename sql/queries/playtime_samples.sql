@@ -0,0 +1,35 @@
+-- name: AddPlaytimeSamples :exec
+INSERT INTO playtime_samples (steam_id, app_id, sampled_at, name, playtime_forever, playtime_2weeks)
+SELECT
+    sqlc.arg(steam_id),
+    unnest(sqlc.arg(app_ids)::int[]),
+    sqlc.arg(sampled_at),
+    unnest(sqlc.arg(names)::text[]),
+    unnest(sqlc.arg(playtimes_forever)::int[]),
+    unnest(sqlc.arg(playtimes_2weeks)::int[]);
+
+-- name: DeleteUnclaimedPlaytimeSamples :exec
+DELETE FROM playtime_samples
+WHERE steam_id = $1
+    AND NOT EXISTS (SELECT 1 FROM users WHERE users.steam_id = $1);
+
+-- name: ListLatestPlaytimeSamples :many
+SELECT DISTINCT ON (app_id) * FROM playtime_samples
+WHERE steam_id = sqlc.arg(steam_id) AND sampled_at <= sqlc.arg(before)
+ORDER BY app_id, sampled_at DESC;
+
+-- name: ListPlaytimeSamples :many
+SELECT * FROM playtime_samples
+WHERE steam_id = sqlc.arg(steam_id) AND sampled_at > sqlc.arg(after) AND sampled_at <= sqlc.arg(until)
+ORDER BY app_id, sampled_at;
+
+-- name: ListLatestAppPlaytimeSamples :many
+SELECT DISTINCT ON (steam_id) * FROM playtime_samples
+WHERE steam_id = ANY(sqlc.arg(steam_ids)::text[]) AND app_id = sqlc.arg(app_id) AND sampled_at <= sqlc.arg(before)
+ORDER BY steam_id, sampled_at DESC;
+
+-- name: ListAppPlaytimeSamples :many
+SELECT * FROM playtime_samples
+WHERE steam_id = ANY(sqlc.arg(steam_ids)::text[]) AND app_id = sqlc.arg(app_id)
+    AND sampled_at > sqlc.arg(after) AND sampled_at <= sqlc.arg(until)
+ORDER BY steam_id, sampled_at;
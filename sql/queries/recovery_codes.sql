@@ -0,0 +1,22 @@
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, created_at, user_id, code_hash)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = $3
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: ListRecoveryCodesForUser :many
+SELECT * FROM recovery_codes
+WHERE user_id = $1
+ORDER BY created_at;
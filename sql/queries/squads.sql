@@ -0,0 +1,56 @@
+-- name: CreateSquad :one
+INSERT INTO squads (id, created_at, updated_at, user_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetSquadForUser :one
+SELECT * FROM squads
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListSquadsForUser :many
+SELECT * FROM squads
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: CountSquadsForUser :one
+SELECT COUNT(*) FROM squads
+WHERE user_id = $1;
+
+-- name: RenameSquad :one
+UPDATE squads
+SET name = $3, updated_at = $4
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteSquad :execrows
+DELETE FROM squads
+WHERE id = $1 AND user_id = $2;
+
+-- name: AddSquadMember :exec
+INSERT INTO squad_members (squad_id, steam_id, position)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: DeleteSquadMembers :exec
+DELETE FROM squad_members
+WHERE squad_id = $1;
+
+-- name: ListSquadMembers :many
+SELECT * FROM squad_members
+WHERE squad_id = $1
+ORDER BY position;
+
+-- name: ListSquadMembersForUser :many
+SELECT squad_members.* FROM squad_members
+JOIN squads ON squads.id = squad_members.squad_id
+WHERE squads.user_id = $1
+ORDER BY squad_members.squad_id, squad_members.position;
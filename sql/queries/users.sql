@@ -18,16 +18,107 @@ SELECT * FROM users WHERE username = $1;
 SELECT * FROM users WHERE id = $1;
 --
 
--- name: UpdateUser :exec
+-- name: UpdateUser :one
 UPDATE users
 SET
     username = COALESCE(NULLIF($1::text, ''), username),
     hashed_password = COALESCE(NULLIF($2::text, ''), hashed_password),
     steam_id = COALESCE(NULLIF($3::text, ''), steam_id),
     updated_at = $4
-WHERE id = $5;
+WHERE id = $5
+RETURNING *;
+--
+
+-- name: GetUserByIDForUpdate :one
+SELECT * FROM users WHERE id = $1 FOR UPDATE;
 --
 
 -- name: DeleteUsers :exec
 DELETE FROM users;
---
\ No newline at end of file
+--
+
+-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, updated_at = $3
+WHERE id = $1;
+--
+
+-- name: EnableTOTP :exec
+UPDATE users
+SET totp_enabled = TRUE, updated_at = $2
+WHERE id = $1;
+--
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled = FALSE, updated_at = $2
+WHERE id = $1;
+--
+
+-- name: GetUserByEmail :one
+SELECT * FROM users WHERE email = $1 AND email_verified_at IS NOT NULL;
+--
+
+-- name: SetPendingEmail :exec
+UPDATE users
+SET pending_email = $2, updated_at = $3
+WHERE id = $1;
+--
+
+-- name: MarkEmailVerified :execrows
+UPDATE users
+SET email = pending_email, pending_email = NULL, email_verified_at = $2, updated_at = $2
+WHERE id = $1 AND pending_email IS NOT NULL;
+--
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = $3
+WHERE id = $1;
+--
+
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY created_at
+LIMIT $1 OFFSET $2;
+--
+
+-- name: SetUserRoleByUsername :execrows
+UPDATE users
+SET role = $2, updated_at = $3
+WHERE username = $1;
+--
+
+-- name: SetUserDisabled :execrows
+UPDATE users
+SET disabled_at = $2, updated_at = $3
+WHERE id = $1;
+--
+
+-- name: RevokeUserSessions :execrows
+UPDATE users
+SET sessions_revoked_at = $2
+WHERE id = $1;
+--
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1;
+--
+
+-- name: RehashUserPassword :execrows
+UPDATE users
+SET hashed_password = sqlc.arg(new_hash)
+WHERE id = sqlc.arg(id) AND hashed_password = sqlc.arg(old_hash);
+--
+
+-- name: ListActiveSteamIDs :many
+SELECT DISTINCT steam_id FROM users
+WHERE disabled_at IS NULL
+ORDER BY steam_id;
+--
+
+-- name: UseTOTPCounter :execrows
+UPDATE users
+SET totp_last_counter = sqlc.arg(counter)
+WHERE id = sqlc.arg(id) AND totp_last_counter < sqlc.arg(counter);
+--
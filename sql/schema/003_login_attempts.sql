@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE login_attempts (
+    scope TEXT NOT NULL,
+    identifier TEXT NOT NULL,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP DEFAULT NULL,
+    PRIMARY KEY (scope, identifier)
+);
+
+-- +goose Down
+DROP TABLE login_attempts;
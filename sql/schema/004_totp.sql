@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN totp_secret TEXT DEFAULT NULL,
+    ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    -- counter of the last accepted code, so a code can't be used twice
+    ADD COLUMN totp_last_counter BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP DEFAULT NULL,
+    UNIQUE (user_id, code_hash),
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+
+ALTER TABLE users
+    DROP COLUMN totp_last_counter,
+    DROP COLUMN totp_enabled,
+    DROP COLUMN totp_secret;
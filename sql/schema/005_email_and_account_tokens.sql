@@ -0,0 +1,32 @@
+-- +goose Up
+-- A new address is kept in pending_email until its verification link is used, so email only ever holds a
+-- verified address. Only verified emails have to be unique, otherwise anyone could claim someone else's
+-- address before they do
+ALTER TABLE users
+    ADD COLUMN email TEXT DEFAULT NULL,
+    ADD COLUMN email_verified_at TIMESTAMP DEFAULT NULL,
+    ADD COLUMN pending_email TEXT DEFAULT NULL;
+
+CREATE UNIQUE INDEX users_verified_email_key ON users (email) WHERE email_verified_at IS NOT NULL;
+
+CREATE TABLE account_tokens (
+    token_hash TEXT PRIMARY KEY,
+    purpose TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE account_tokens;
+
+DROP INDEX users_verified_email_key;
+
+ALTER TABLE users
+    DROP COLUMN pending_email,
+    DROP COLUMN email_verified_at,
+    DROP COLUMN email;
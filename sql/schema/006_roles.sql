@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+    ADD COLUMN disabled_at TIMESTAMP DEFAULT NULL,
+    ADD COLUMN sessions_revoked_at TIMESTAMP DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN sessions_revoked_at,
+    DROP COLUMN disabled_at,
+    DROP COLUMN role;
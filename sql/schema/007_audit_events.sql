@@ -0,0 +1,69 @@
+-- +goose Up
+-- user_id and actor_id deliberately have no foreign keys, events have to outlive the accounts they describe
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID DEFAULT NULL,
+    actor_id UUID DEFAULT NULL,
+    event_type TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX audit_events_user_id_created_at_idx ON audit_events (user_id, created_at DESC);
+CREATE INDEX audit_events_event_type_created_at_idx ON audit_events (event_type, created_at DESC);
+
+-- Audit events are append-only, the one exception is pseudonymize_audit_events stripping the personal data of a
+-- deleted account. It swaps the account's ID for a random one, so its events still line up with each other but
+-- can't be tied back to the account, and drops the IP, user agent and any names or emails in the details
+-- +goose StatementBegin
+CREATE FUNCTION prevent_audit_event_changes() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND current_setting('steam_lens.pseudonymizing_audit_events', true) = 'on'
+        AND NEW.id = OLD.id
+        AND NEW.created_at = OLD.created_at
+        AND NEW.event_type = OLD.event_type THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION prevent_audit_event_changes();
+
+-- +goose StatementBegin
+CREATE FUNCTION pseudonymize_audit_events(target_user_id UUID, target_username TEXT) RETURNS BIGINT AS $$
+DECLARE
+    pseudonym UUID := gen_random_uuid();
+    updated BIGINT;
+BEGIN
+    PERFORM set_config('steam_lens.pseudonymizing_audit_events', 'on', true);
+
+    UPDATE audit_events
+    SET user_id = CASE WHEN user_id = target_user_id THEN pseudonym ELSE user_id END,
+        actor_id = CASE WHEN actor_id = target_user_id THEN pseudonym ELSE actor_id END,
+        ip = '',
+        user_agent = '',
+        details = details - ARRAY['username', 'email', 'old', 'new']
+    WHERE user_id = target_user_id
+        OR actor_id = target_user_id
+        -- Failed logins for a username are recorded without a user ID
+        OR (user_id IS NULL AND details->>'username' = target_username);
+    GET DIAGNOSTICS updated = ROW_COUNT;
+
+    PERFORM set_config('steam_lens.pseudonymizing_audit_events', 'off', true);
+    RETURN updated;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION pseudonymize_audit_events(UUID, TEXT);
+DROP TRIGGER audit_events_append_only ON audit_events;
+DROP FUNCTION prevent_audit_event_changes();
+DROP TABLE audit_events;
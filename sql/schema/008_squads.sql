@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE squads (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name),
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE squad_members (
+    squad_id UUID NOT NULL,
+    steam_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (squad_id, steam_id),
+    FOREIGN KEY (squad_id)
+        REFERENCES squads(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE squad_members;
+DROP TABLE squads;
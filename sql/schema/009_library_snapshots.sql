@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE library_snapshots (
+    id UUID PRIMARY KEY,
+    steam_id TEXT NOT NULL,
+    taken_at TIMESTAMP NOT NULL,
+    game_count INTEGER NOT NULL,
+    source TEXT NOT NULL CHECK (source IN ('manual', 'scheduled')),
+    created_by UUID DEFAULT NULL,
+    FOREIGN KEY (created_by)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX library_snapshots_steam_id_taken_at_idx ON library_snapshots (steam_id, taken_at DESC);
+
+CREATE TABLE library_snapshot_games (
+    snapshot_id UUID NOT NULL,
+    app_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    playtime_forever INTEGER NOT NULL,
+    PRIMARY KEY (snapshot_id, app_id),
+    FOREIGN KEY (snapshot_id)
+        REFERENCES library_snapshots(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE library_snapshot_games;
+DROP TABLE library_snapshots;
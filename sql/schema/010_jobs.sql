@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE job_leases (
+    name TEXT PRIMARY KEY,
+    owner TEXT NOT NULL,
+    leased_until TIMESTAMP NOT NULL
+);
+
+CREATE TABLE job_runs (
+    id BIGSERIAL PRIMARY KEY,
+    job_name TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    error TEXT DEFAULT NULL
+);
+
+CREATE INDEX job_runs_job_name_started_at_idx ON job_runs (job_name, started_at DESC);
+
+-- +goose Down
+DROP TABLE job_runs;
+DROP TABLE job_leases;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE playtime_samples (
+    steam_id TEXT NOT NULL,
+    app_id INTEGER NOT NULL,
+    sampled_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    playtime_forever INTEGER NOT NULL,
+    playtime_2weeks INTEGER NOT NULL,
+    PRIMARY KEY (steam_id, app_id, sampled_at)
+);
+
+CREATE INDEX playtime_samples_app_id_idx ON playtime_samples (app_id, steam_id, sampled_at);
+
+-- +goose Down
+DROP TABLE playtime_samples;
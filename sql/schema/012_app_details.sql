@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE app_details (
+    app_id INTEGER PRIMARY KEY,
+    found BOOLEAN NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    is_free BOOLEAN NOT NULL,
+    genres TEXT[] NOT NULL,
+    categories TEXT[] NOT NULL,
+    multiplayer BOOLEAN NOT NULL,
+    coop BOOLEAN NOT NULL,
+    price_currency TEXT DEFAULT NULL,
+    price_initial INTEGER DEFAULT NULL,
+    price_final INTEGER DEFAULT NULL,
+    discount_percent INTEGER DEFAULT NULL,
+    fetched_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE app_details;
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Khazz0r/steam-lens/internal/api"
+	"github.com/Khazz0r/steam-lens/internal/validate"
+)
+
+// Decodes the JSON body into params and then runs the rules against it. A body that can't be decoded
+// gets a 400 and a body that breaks any rule gets a 422 listing every invalid field.
+// Returns false when a response has already been written
+func decodeAndValidate(w http.ResponseWriter, req *http.Request, params any, rules func(v *validate.Validator)) bool {
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(params)
+	if err != nil {
+		api.RespondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return false
+	}
+
+	v := validate.New()
+	if rules != nil {
+		rules(v)
+	}
+
+	if !v.Valid() {
+		respondWithValidationErrors(w, v.Errors)
+		return false
+	}
+
+	return true
+}
+
+func respondWithValidationErrors(w http.ResponseWriter, fieldErrors []validate.FieldError) {
+	api.RespondWithAppError(w, &api.Error{
+		Status:  http.StatusUnprocessableEntity,
+		Code:    api.CodeValidationFailed,
+		Message: "Request failed validation",
+		Details: map[string]any{"fields": fieldErrors},
+	})
+}